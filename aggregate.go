@@ -0,0 +1,485 @@
+package timeindex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// Aggregator accumulates the items that fall into one bucket. `Add` is called
+// once for every item (or once with a nil item for an entry without items),
+// in time order.
+type Aggregator interface {
+	Add(t time.Time, item interface{})
+
+	// Result returns the aggregate. `ok` is false if there is nothing to
+	// report (e.g. a minimum of no values).
+	Result() (value interface{}, ok bool)
+
+	// Reset prepares the aggregator for the next bucket.
+	Reset()
+}
+
+// NumericExtractor returns the numeric value of an item. `ok` is false if the
+// item should be ignored.
+type NumericExtractor func(item interface{}) (value float64, ok bool)
+
+// NumericItem is the default `NumericExtractor`. It accepts the built-in
+// integer and floating-point types and ignores anything else.
+func NumericItem(item interface{}) (value float64, ok bool) {
+	switch v := item.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+type countAggregator struct {
+	count int
+}
+
+// NewCountAggregator returns an `Aggregator` whose result is the number (int)
+// of items, counting an entry without items as one. An empty bucket has no
+// result (fill it with zero to report it).
+func NewCountAggregator() Aggregator {
+	return new(countAggregator)
+}
+
+func (ca *countAggregator) Add(t time.Time, item interface{}) {
+	ca.count++
+}
+
+func (ca *countAggregator) Remove(t time.Time, item interface{}) {
+	ca.count--
+}
+
+func (ca *countAggregator) Result() (value interface{}, ok bool) {
+	return ca.count, ca.count > 0
+}
+
+func (ca *countAggregator) Reset() {
+	ca.count = 0
+}
+
+type sumAggregator struct {
+	isMean    bool
+	extractor NumericExtractor
+
+	count int
+	sum   float64
+}
+
+// NewSumAggregator returns an `Aggregator` whose result is the sum (float64)
+// of the numeric items. If `extractor` is nil, `NumericItem` is used.
+func NewSumAggregator(extractor NumericExtractor) Aggregator {
+	if extractor == nil {
+		extractor = NumericItem
+	}
+
+	return &sumAggregator{
+		isMean:    false,
+		extractor: extractor,
+	}
+}
+
+// NewMeanAggregator returns an `Aggregator` whose result is the mean
+// (float64) of the numeric items.
+func NewMeanAggregator(extractor NumericExtractor) Aggregator {
+	if extractor == nil {
+		extractor = NumericItem
+	}
+
+	return &sumAggregator{
+		isMean:    true,
+		extractor: extractor,
+	}
+}
+
+func (sa *sumAggregator) Add(t time.Time, item interface{}) {
+	value, ok := sa.extractor(item)
+	if ok == false {
+		return
+	}
+
+	sa.count++
+	sa.sum += value
+}
+
+func (sa *sumAggregator) Remove(t time.Time, item interface{}) {
+	value, ok := sa.extractor(item)
+	if ok == false {
+		return
+	}
+
+	sa.count--
+	sa.sum -= value
+
+	if sa.count == 0 {
+		// Don't let rounding errors accumulate.
+		sa.sum = 0
+	}
+}
+
+func (sa *sumAggregator) Result() (value interface{}, ok bool) {
+	if sa.count == 0 {
+		return nil, false
+	} else if sa.isMean == true {
+		return sa.sum / float64(sa.count), true
+	}
+
+	return sa.sum, true
+}
+
+func (sa *sumAggregator) Reset() {
+	sa.count = 0
+	sa.sum = 0
+}
+
+type extremeCandidate struct {
+	index int
+	value float64
+}
+
+type extremeAggregator struct {
+	isMax     bool
+	extractor NumericExtractor
+
+	// candidates is a monotonic deque of the values that can still become
+	// the extreme as older items are removed. The current extreme is at
+	// `head`.
+	candidates []extremeCandidate
+	head       int
+
+	// added and removed count the items so that a removed item can be
+	// matched to its candidate.
+	added   int
+	removed int
+}
+
+// NewMinAggregator returns an `Aggregator` whose result is the minimum
+// (float64) of the numeric items.
+func NewMinAggregator(extractor NumericExtractor) Aggregator {
+	if extractor == nil {
+		extractor = NumericItem
+	}
+
+	return &extremeAggregator{
+		isMax:      false,
+		extractor:  extractor,
+		candidates: make([]extremeCandidate, 0),
+	}
+}
+
+// NewMaxAggregator returns an `Aggregator` whose result is the maximum
+// (float64) of the numeric items.
+func NewMaxAggregator(extractor NumericExtractor) Aggregator {
+	if extractor == nil {
+		extractor = NumericItem
+	}
+
+	return &extremeAggregator{
+		isMax:      true,
+		extractor:  extractor,
+		candidates: make([]extremeCandidate, 0),
+	}
+}
+
+func (ea *extremeAggregator) Add(t time.Time, item interface{}) {
+	index := ea.added
+	ea.added++
+
+	value, ok := ea.extractor(item)
+	if ok == false {
+		return
+	}
+
+	// Older candidates that aren't more extreme than the new value can never
+	// be the extreme again.
+	for last := len(ea.candidates) - 1; last >= ea.head; last-- {
+		previous := ea.candidates[last].value
+		if (ea.isMax == true && previous > value) || (ea.isMax == false && previous < value) {
+			break
+		}
+
+		ea.candidates = ea.candidates[:last]
+	}
+
+	ea.candidates = append(ea.candidates, extremeCandidate{index: index, value: value})
+}
+
+// Remove removes the oldest item.
+func (ea *extremeAggregator) Remove(t time.Time, item interface{}) {
+	index := ea.removed
+	ea.removed++
+
+	if ea.head < len(ea.candidates) && ea.candidates[ea.head].index == index {
+		ea.head++
+	}
+
+	// Reclaim the space of the removed candidates once they are the majority.
+	if ea.head > len(ea.candidates)/2 {
+		ea.candidates = append(ea.candidates[:0], ea.candidates[ea.head:]...)
+		ea.head = 0
+	}
+}
+
+func (ea *extremeAggregator) Result() (value interface{}, ok bool) {
+	if ea.head == len(ea.candidates) {
+		return nil, false
+	}
+
+	return ea.candidates[ea.head].value, true
+}
+
+func (ea *extremeAggregator) Reset() {
+	ea.candidates = ea.candidates[:0]
+	ea.head = 0
+	ea.added = 0
+	ea.removed = 0
+}
+
+type firstAggregator struct {
+	item  interface{}
+	found bool
+}
+
+// NewFirstAggregator returns an `Aggregator` whose result is the earliest
+// item. Only that item is kept, but a sliding window swaps it for one that
+// holds the items in the window.
+func NewFirstAggregator() Aggregator {
+	return &firstAggregator{}
+}
+
+func (fa *firstAggregator) Add(t time.Time, item interface{}) {
+	if fa.found == false {
+		fa.item = item
+		fa.found = true
+	}
+}
+
+func (fa *firstAggregator) Result() (value interface{}, ok bool) {
+	return fa.item, fa.found
+}
+
+func (fa *firstAggregator) Reset() {
+	fa.item = nil
+	fa.found = false
+}
+
+func (fa *firstAggregator) windowAggregator() WindowAggregator {
+	return &windowFirstAggregator{
+		items: make([]interface{}, 0),
+	}
+}
+
+// windowFirstAggregator is the `WindowAggregator` for the earliest item. It
+// holds the items until they are removed.
+type windowFirstAggregator struct {
+	// items are the items that haven't been removed, starting at `head`.
+	items []interface{}
+	head  int
+}
+
+func (wfa *windowFirstAggregator) Add(t time.Time, item interface{}) {
+	wfa.items = append(wfa.items, item)
+}
+
+// Remove removes the oldest item.
+func (wfa *windowFirstAggregator) Remove(t time.Time, item interface{}) {
+	if wfa.head < len(wfa.items) {
+		wfa.items[wfa.head] = nil
+		wfa.head++
+	}
+
+	if wfa.head > len(wfa.items)/2 {
+		wfa.items = append(wfa.items[:0], wfa.items[wfa.head:]...)
+		wfa.head = 0
+	}
+}
+
+func (wfa *windowFirstAggregator) Result() (value interface{}, ok bool) {
+	if wfa.head == len(wfa.items) {
+		return nil, false
+	}
+
+	return wfa.items[wfa.head], true
+}
+
+func (wfa *windowFirstAggregator) Reset() {
+	wfa.items = wfa.items[:0]
+	wfa.head = 0
+}
+
+type lastAggregator struct {
+	item  interface{}
+	count int
+}
+
+// NewLastAggregator returns an `Aggregator` whose result is the latest item.
+func NewLastAggregator() Aggregator {
+	return &lastAggregator{}
+}
+
+func (la *lastAggregator) Add(t time.Time, item interface{}) {
+	la.item = item
+	la.count++
+}
+
+// Remove removes the oldest item. The latest item only changes once there are
+// no items left.
+func (la *lastAggregator) Remove(t time.Time, item interface{}) {
+	if la.count > 0 {
+		la.count--
+	}
+
+	if la.count == 0 {
+		la.item = nil
+	}
+}
+
+func (la *lastAggregator) Result() (value interface{}, ok bool) {
+	return la.item, la.count > 0
+}
+
+func (la *lastAggregator) Reset() {
+	la.item = nil
+	la.count = 0
+}
+
+// BucketResult is the aggregate of one bucket, [From, To).
+type BucketResult struct {
+	From  time.Time
+	To    time.Time
+	Value interface{}
+
+	// IsFilled indicates that the bucket had no result and `Value` is the
+	// fill value.
+	IsFilled bool
+}
+
+// walkBuckets calls the callback with the aggregate of every bucket between
+// `from` and `to`, visiting the entries once. `next` returns the end of the
+// bucket starting at the given time. Buckets without a result are skipped
+// unless `fill` is not nil.
+func (ts TimeSlice) walkBuckets(
+	from, to time.Time, next func(start time.Time) time.Time,
+	aggregator Aggregator, fill *interface{}, cb func(br BucketResult) error,
+) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if from.Before(to) == false {
+		log.Panicf("bucket range is invalid: [%s] - [%s]", from, to)
+	}
+
+	i := ts.Search(from)
+
+	for start := from; start.Before(to) == true; {
+		end := next(start)
+		if end.After(start) == false {
+			log.Panicf("bucket does not advance: [%s]", start)
+		} else if end.After(to) == true {
+			end = to
+		}
+
+		aggregator.Reset()
+
+		for ; i < len(ts) && ts[i].Time.Before(end) == true; i++ {
+			te := ts[i]
+
+			if len(te.Items) == 0 {
+				aggregator.Add(te.Time, nil)
+				continue
+			}
+
+			for _, item := range te.Items {
+				aggregator.Add(te.Time, item)
+			}
+		}
+
+		br := BucketResult{
+			From: start,
+			To:   end,
+		}
+
+		value, ok := aggregator.Result()
+		if ok == true {
+			br.Value = value
+		} else if fill != nil {
+			br.Value = *fill
+			br.IsFilled = true
+		} else {
+			start = end
+			continue
+		}
+
+		err := cb(br)
+		log.PanicIf(err)
+
+		start = end
+	}
+
+	return nil
+}
+
+// Bucket divides [from, to) into buckets of the given width (the last one may
+// be shorter) and calls the callback with the aggregate of every bucket that
+// has a result. The entries are visited once.
+func (ts TimeSlice) Bucket(
+	from, to time.Time, width time.Duration, aggregator Aggregator,
+	cb func(br BucketResult) error,
+) (err error) {
+	if width <= 0 {
+		return fmt.Errorf("bucket width must be positive: [%s]", width)
+	}
+
+	next := func(start time.Time) time.Time {
+		return start.Add(width)
+	}
+
+	return ts.walkBuckets(from, to, next, aggregator, nil, cb)
+}
+
+// BucketWithFill is like `Bucket` but also calls the callback for the buckets
+// without a result, with `fill` as the value.
+func (ts TimeSlice) BucketWithFill(
+	from, to time.Time, width time.Duration, aggregator Aggregator,
+	fill interface{}, cb func(br BucketResult) error,
+) (err error) {
+	if width <= 0 {
+		return fmt.Errorf("bucket width must be positive: [%s]", width)
+	}
+
+	next := func(start time.Time) time.Time {
+		return start.Add(width)
+	}
+
+	return ts.walkBuckets(from, to, next, aggregator, &fill, cb)
+}
@@ -0,0 +1,161 @@
+package timeindex
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getBucketTestSlice() (ts TimeSlice, start time.Time) {
+	start = getTestEpoch()
+
+	ts = make(TimeSlice, 0)
+	ts = ts.Add(start.Add(time.Second*10), 3)
+	ts = ts.Add(start.Add(time.Second*10), 5)
+	ts = ts.Add(start.Add(time.Second*50), 1.5)
+	ts = ts.Add(start.Add(time.Minute*2+time.Second), "not a number")
+	ts = ts.Add(start.Add(time.Minute*3), nil)
+
+	// Outside of the range.
+	ts = ts.Add(start.Add(-time.Second), 100)
+	ts = ts.Add(start.Add(time.Minute*4), 100)
+
+	return ts, start
+}
+
+func collectBuckets(fn func(cb func(br BucketResult) error) error) (results []BucketResult) {
+	results = make([]BucketResult, 0)
+
+	cb := func(br BucketResult) error {
+		results = append(results, br)
+		return nil
+	}
+
+	err := fn(cb)
+	log.PanicIf(err)
+
+	return results
+}
+
+func TestTimeSlice_Bucket_Count(t *testing.T) {
+	ts, start := getBucketTestSlice()
+
+	results := collectBuckets(func(cb func(br BucketResult) error) error {
+		return ts.Bucket(start, start.Add(time.Minute*4), time.Minute, NewCountAggregator(), cb)
+	})
+
+	if len(results) != 3 {
+		t.Fatalf("Bucket count not correct: %v", results)
+	}
+
+	expected := []struct {
+		from  time.Duration
+		value int
+	}{
+		{0, 3},
+		{time.Minute * 2, 1},
+		{time.Minute * 3, 1},
+	}
+
+	for i, e := range expected {
+		br := results[i]
+		if br.From.Equal(start.Add(e.from)) == false || br.To.Equal(start.Add(e.from+time.Minute)) == false || br.Value != e.value || br.IsFilled == true {
+			t.Fatalf("Bucket (%d) not correct: %v", i, br)
+		}
+	}
+}
+
+func TestTimeSlice_BucketWithFill(t *testing.T) {
+	ts, start := getBucketTestSlice()
+
+	results := collectBuckets(func(cb func(br BucketResult) error) error {
+		return ts.BucketWithFill(start, start.Add(time.Minute*4), time.Minute, NewCountAggregator(), 0, cb)
+	})
+
+	values := make([]interface{}, len(results))
+	for i, br := range results {
+		values[i] = br.Value
+	}
+
+	if reflect.DeepEqual(values, []interface{}{3, 0, 1, 1}) == false {
+		t.Fatalf("Values not correct: %v", values)
+	} else if results[1].IsFilled == false || results[0].IsFilled == true {
+		t.Fatalf("Fill flags not correct: %v", results)
+	}
+}
+
+func TestTimeSlice_Bucket_Numeric(t *testing.T) {
+	ts, start := getBucketTestSlice()
+
+	cases := []struct {
+		aggregator Aggregator
+		expected   []interface{}
+	}{
+		{NewSumAggregator(nil), []interface{}{9.5}},
+		{NewMinAggregator(nil), []interface{}{1.5}},
+		{NewMaxAggregator(nil), []interface{}{5.0}},
+		{NewMeanAggregator(nil), []interface{}{9.5 / 3}},
+		{NewFirstAggregator(), []interface{}{3, "not a number", nil}},
+		{NewLastAggregator(), []interface{}{1.5, "not a number", nil}},
+	}
+
+	for i, c := range cases {
+		results := collectBuckets(func(cb func(br BucketResult) error) error {
+			return ts.Bucket(start, start.Add(time.Minute*4), time.Minute, c.aggregator, cb)
+		})
+
+		values := make([]interface{}, len(results))
+		for j, br := range results {
+			values[j] = br.Value
+		}
+
+		if reflect.DeepEqual(values, c.expected) == false {
+			t.Fatalf("Case (%d) not correct: %v", i, values)
+		}
+	}
+}
+
+func TestTimeSlice_Bucket_CustomExtractor(t *testing.T) {
+	ts, start := getBucketTestSlice()
+
+	isString := func(item interface{}) (float64, bool) {
+		_, ok := item.(string)
+		return 1, ok
+	}
+
+	results := collectBuckets(func(cb func(br BucketResult) error) error {
+		return ts.Bucket(start, start.Add(time.Minute*4), time.Minute, NewSumAggregator(isString), cb)
+	})
+
+	if len(results) != 1 || results[0].Value != 1.0 || results[0].From.Equal(start.Add(time.Minute*2)) == false {
+		t.Fatalf("Results not correct: %v", results)
+	}
+}
+
+func TestTimeSlice_Bucket_PartialLastBucket(t *testing.T) {
+	ts, start := getBucketTestSlice()
+
+	results := collectBuckets(func(cb func(br BucketResult) error) error {
+		return ts.BucketWithFill(start, start.Add(time.Minute*4), time.Minute*3, NewCountAggregator(), 0, cb)
+	})
+
+	if len(results) != 2 || results[1].To.Equal(start.Add(time.Minute*4)) == false || results[1].Value != 1 {
+		t.Fatalf("Results not correct: %v", results)
+	}
+}
+
+func TestTimeSlice_Bucket_Invalid(t *testing.T) {
+	ts, start := getBucketTestSlice()
+
+	cb := func(br BucketResult) error {
+		return nil
+	}
+
+	if err := ts.Bucket(start, start.Add(time.Minute), 0, NewCountAggregator(), cb); err == nil {
+		t.Fatalf("Expected error for zero width.")
+	} else if err := ts.Bucket(start, start, time.Minute, NewCountAggregator(), cb); err == nil {
+		t.Fatalf("Expected error for empty range.")
+	}
+}
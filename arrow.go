@@ -0,0 +1,680 @@
+package timeindex
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// Export to and import from the Arrow IPC streaming format
+// (https://arrow.apache.org/docs/format/Columnar.html#ipc-streaming-format).
+// A `TimeSlice` is written with a "time" column and a `TimeIntervalSlice` with
+// "from" and "to" columns (all non-nullable nanosecond timestamps in UTC),
+// followed by an "items" column holding the JSON array of each row's items as
+// encoded by a `JsonItemCodec`.
+
+const (
+	// arrowBatchSize is the number of rows in each record batch.
+	arrowBatchSize = 65536
+
+	arrowContinuation = 0xffffffff
+
+	arrowMetadataVersionV5 = 4
+
+	arrowMessageHeaderSchema      = 1
+	arrowMessageHeaderRecordBatch = 3
+
+	arrowTypeBinary    = 4
+	arrowTypeUtf8      = 5
+	arrowTypeTimestamp = 10
+
+	arrowTimeUnitSecond      = 0
+	arrowTimeUnitMillisecond = 1
+	arrowTimeUnitMicrosecond = 2
+	arrowTimeUnitNanosecond  = 3
+)
+
+// arrowColumn is one column of a record batch.
+type arrowColumn interface {
+	// buffers returns the validity, offsets (if any), and data buffers.
+	buffers() [][]byte
+}
+
+type arrowTimestampColumn []int64
+
+func (atc arrowTimestampColumn) buffers() [][]byte {
+	data := make([]byte, len(atc)*8)
+	for i, n := range atc {
+		binary.LittleEndian.PutUint64(data[i*8:], uint64(n))
+	}
+
+	return [][]byte{nil, data}
+}
+
+type arrowUtf8Column [][]byte
+
+func (auc arrowUtf8Column) buffers() [][]byte {
+	offsets := make([]byte, (len(auc)+1)*4)
+
+	size := 0
+	for _, value := range auc {
+		size += len(value)
+	}
+
+	data := make([]byte, 0, size)
+	for i, value := range auc {
+		binary.LittleEndian.PutUint32(offsets[i*4:], uint32(len(data)))
+		data = append(data, value...)
+	}
+
+	binary.LittleEndian.PutUint32(offsets[len(auc)*4:], uint32(len(data)))
+
+	return [][]byte{nil, offsets, data}
+}
+
+func arrowTimestampField(name string) *fbTable {
+	timestamp := newFbTable().
+		addInt16(0, arrowTimeUnitNanosecond).
+		addChild(1, fbString("UTC"))
+
+	return newFbTable().
+		addChild(0, fbString(name)).
+		addUint8(1, 0).
+		addUint8(2, arrowTypeTimestamp).
+		addChild(3, timestamp).
+		addChild(5, fbTableVector{})
+}
+
+func arrowUtf8Field(name string) *fbTable {
+	return newFbTable().
+		addChild(0, fbString(name)).
+		addUint8(1, 0).
+		addUint8(2, arrowTypeUtf8).
+		addChild(3, newFbTable()).
+		addChild(5, fbTableVector{})
+}
+
+func writeArrowMessage(w io.Writer, headerType uint8, header *fbTable, body []byte) (err error) {
+	message := newFbTable().
+		addInt16(0, arrowMetadataVersionV5).
+		addUint8(1, headerType).
+		addChild(2, header).
+		addInt64(3, int64(len(body)))
+
+	metadata := finishFlatbuffer(message)
+	for len(metadata)%8 != 0 {
+		metadata = append(metadata, 0)
+	}
+
+	prefix := make([]byte, 8)
+	binary.LittleEndian.PutUint32(prefix, arrowContinuation)
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(len(metadata)))
+
+	for _, part := range [][]byte{prefix, metadata, body} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeArrowSchema(w io.Writer, fields []*fbTable) (err error) {
+	schema := newFbTable().
+		addInt16(0, 0).
+		addChild(1, fbTableVector(fields))
+
+	return writeArrowMessage(w, arrowMessageHeaderSchema, schema, nil)
+}
+
+func writeArrowRecordBatch(w io.Writer, length int, columns []arrowColumn) (err error) {
+	nodes := &fbStructVector{
+		elementAlignment: 8,
+		count:            len(columns),
+		data:             make([]byte, 0, len(columns)*16),
+	}
+
+	buffers := &fbStructVector{
+		elementAlignment: 8,
+		data:             make([]byte, 0),
+	}
+
+	body := make([]byte, 0)
+	element := make([]byte, 16)
+
+	for _, column := range columns {
+		binary.LittleEndian.PutUint64(element, uint64(length))
+		binary.LittleEndian.PutUint64(element[8:], 0)
+		nodes.data = append(nodes.data, element...)
+
+		for _, buffer := range column.buffers() {
+			binary.LittleEndian.PutUint64(element, uint64(len(body)))
+			binary.LittleEndian.PutUint64(element[8:], uint64(len(buffer)))
+			buffers.data = append(buffers.data, element...)
+			buffers.count++
+
+			body = append(body, buffer...)
+			for len(body)%8 != 0 {
+				body = append(body, 0)
+			}
+		}
+	}
+
+	recordBatch := newFbTable().
+		addInt64(0, int64(length)).
+		addChild(1, nodes).
+		addChild(2, buffers)
+
+	return writeArrowMessage(w, arrowMessageHeaderRecordBatch, recordBatch, body)
+}
+
+func writeArrowEndOfStream(w io.Writer) (err error) {
+	eos := make([]byte, 8)
+	binary.LittleEndian.PutUint32(eos, arrowContinuation)
+
+	_, err = w.Write(eos)
+	return err
+}
+
+func encodeArrowItems(items []interface{}, codec JsonItemCodec) (encoded []byte, err error) {
+	raws, err := encodeJsonItems(items, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(raws)
+}
+
+func decodeArrowItems(encoded []byte, codec JsonItemCodec) (items []interface{}, err error) {
+	if len(encoded) == 0 {
+		return []interface{}{}, nil
+	}
+
+	raws := make([]json.RawMessage, 0)
+	if err := json.Unmarshal(encoded, &raws); err != nil {
+		return nil, err
+	}
+
+	return decodeJsonItems(raws, codec)
+}
+
+// WriteTimeSliceArrow writes the slice as an Arrow IPC stream.
+func WriteTimeSliceArrow(w io.Writer, ts TimeSlice, codec JsonItemCodec) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	fields := []*fbTable{
+		arrowTimestampField("time"),
+		arrowUtf8Field("items"),
+	}
+
+	err = writeArrowSchema(w, fields)
+	log.PanicIf(err)
+
+	for start := 0; start < len(ts); start += arrowBatchSize {
+		end := start + arrowBatchSize
+		if end > len(ts) {
+			end = len(ts)
+		}
+
+		times := make(arrowTimestampColumn, 0, end-start)
+		items := make(arrowUtf8Column, 0, end-start)
+
+		for _, te := range ts[start:end] {
+			n, err := toUnixNano(te.Time)
+			log.PanicIf(err)
+
+			encoded, err := encodeArrowItems(te.Items, codec)
+			log.PanicIf(err)
+
+			times = append(times, n)
+			items = append(items, encoded)
+		}
+
+		err := writeArrowRecordBatch(w, end-start, []arrowColumn{times, items})
+		log.PanicIf(err)
+	}
+
+	err = writeArrowEndOfStream(w)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// WriteTimeIntervalSliceArrow writes the slice as an Arrow IPC stream.
+func WriteTimeIntervalSliceArrow(w io.Writer, tis TimeIntervalSlice, codec JsonItemCodec) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	fields := []*fbTable{
+		arrowTimestampField("from"),
+		arrowTimestampField("to"),
+		arrowUtf8Field("items"),
+	}
+
+	err = writeArrowSchema(w, fields)
+	log.PanicIf(err)
+
+	for start := 0; start < len(tis); start += arrowBatchSize {
+		end := start + arrowBatchSize
+		if end > len(tis) {
+			end = len(tis)
+		}
+
+		froms := make(arrowTimestampColumn, 0, end-start)
+		tos := make(arrowTimestampColumn, 0, end-start)
+		items := make(arrowUtf8Column, 0, end-start)
+
+		for _, ti := range tis[start:end] {
+			from, err := toUnixNano(ti.From)
+			log.PanicIf(err)
+
+			to, err := toUnixNano(ti.To)
+			log.PanicIf(err)
+
+			encoded, err := encodeArrowItems(ti.Items, codec)
+			log.PanicIf(err)
+
+			froms = append(froms, from)
+			tos = append(tos, to)
+			items = append(items, encoded)
+		}
+
+		err := writeArrowRecordBatch(w, end-start, []arrowColumn{froms, tos, items})
+		log.PanicIf(err)
+	}
+
+	err = writeArrowEndOfStream(w)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// arrowFieldType describes a column found in a schema.
+type arrowFieldType struct {
+	name     string
+	typeType uint8
+	timeUnit int16
+}
+
+// arrowStreamReader reads the messages of an IPC stream.
+type arrowStreamReader struct {
+	br     *bufio.Reader
+	fields []arrowFieldType
+}
+
+// readMessage returns the next message and its body, or `io.EOF` at the end
+// of the stream.
+func (asr *arrowStreamReader) readMessage() (message fbTableReader, body []byte, err error) {
+	prefix := make([]byte, 4)
+	if _, err := io.ReadFull(asr.br, prefix); err == io.EOF {
+		return message, nil, io.EOF
+	} else if err != nil {
+		return message, nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(prefix)
+	if size == arrowContinuation {
+		if _, err := io.ReadFull(asr.br, prefix); err != nil {
+			return message, nil, err
+		}
+
+		size = binary.LittleEndian.Uint32(prefix)
+	}
+
+	if size == 0 {
+		return message, nil, io.EOF
+	} else if size > storeMaxRecordSize {
+		return message, nil, fmt.Errorf("arrow message metadata too large")
+	}
+
+	metadata := make([]byte, size)
+	if _, err := io.ReadFull(asr.br, metadata); err != nil {
+		return message, nil, err
+	}
+
+	message = readFlatbufferRoot(metadata)
+
+	bodyLength := message.int64(3, 0)
+	if bodyLength < 0 || bodyLength > storeMaxRecordSize*4 {
+		return message, nil, fmt.Errorf("arrow message body length not valid")
+	}
+
+	body = make([]byte, bodyLength)
+	if _, err := io.ReadFull(asr.br, body); err != nil {
+		return message, nil, err
+	}
+
+	return message, body, nil
+}
+
+func (asr *arrowStreamReader) readSchema(expectedNames []string) (err error) {
+	message, _, err := asr.readMessage()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	}
+
+	if message.uint8(1, 0) != arrowMessageHeaderSchema {
+		return fmt.Errorf("arrow stream does not start with a schema")
+	}
+
+	schema, found := message.table(2)
+	if found == false {
+		return fmt.Errorf("arrow schema missing")
+	} else if schema.int16(0, 0) != 0 {
+		return fmt.Errorf("big-endian arrow streams not supported")
+	}
+
+	elementsPosition, count := schema.vector(1)
+	if count != len(expectedNames) {
+		return fmt.Errorf("arrow schema has (%d) fields but expected (%d)", count, len(expectedNames))
+	}
+
+	asr.fields = make([]arrowFieldType, count)
+	for i := 0; i < count; i++ {
+		field := schema.tableVectorElement(elementsPosition, i)
+
+		aft := arrowFieldType{
+			name:     field.string(0),
+			typeType: field.uint8(2, 0),
+		}
+
+		if aft.name != expectedNames[i] {
+			return fmt.Errorf("arrow field (%d) is [%s] but expected [%s]", i, aft.name, expectedNames[i])
+		}
+
+		if aft.typeType == arrowTypeTimestamp {
+			timestamp, found := field.table(3)
+			if found == false {
+				return fmt.Errorf("arrow timestamp type missing")
+			}
+
+			aft.timeUnit = timestamp.int16(0, 0)
+		}
+
+		isTime := i < len(expectedNames)-1
+		if isTime == true && aft.typeType != arrowTypeTimestamp {
+			return fmt.Errorf("arrow field [%s] is not a timestamp", aft.name)
+		} else if isTime == false && aft.typeType != arrowTypeUtf8 && aft.typeType != arrowTypeBinary {
+			return fmt.Errorf("arrow field [%s] is not a string or binary", aft.name)
+		}
+
+		asr.fields[i] = aft
+	}
+
+	return nil
+}
+
+// arrowRecordBatch is a decoded record batch.
+type arrowRecordBatch struct {
+	length  int
+	times   [][]time.Time
+	items   [][]byte
+	isValid []bool
+}
+
+func arrowBitIsSet(bitmap []byte, i int) bool {
+	return bitmap[i/8]&(1<<uint(i%8)) != 0
+}
+
+// readRecordBatch returns the next batch or `io.EOF`.
+func (asr *arrowStreamReader) readRecordBatch() (arb arrowRecordBatch, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	var message fbTableReader
+	var body []byte
+
+	// Skip anything other than record batches (e.g. dictionaries, which we
+	// don't use).
+	for {
+		message, body, err = asr.readMessage()
+		if err == io.EOF {
+			return arb, io.EOF
+		}
+
+		log.PanicIf(err)
+
+		if message.uint8(1, 0) == arrowMessageHeaderRecordBatch {
+			break
+		}
+	}
+
+	recordBatch, found := message.table(2)
+	if found == false {
+		log.Panicf("arrow record-batch missing")
+	}
+
+	if _, found := recordBatch.table(3); found == true {
+		log.Panicf("compressed arrow record-batches not supported")
+	}
+
+	length := int(recordBatch.int64(0, 0))
+
+	nodesPosition, nodeCount := recordBatch.vector(1)
+	buffersPosition, bufferCount := recordBatch.vector(2)
+
+	if nodeCount != len(asr.fields) {
+		log.Panicf("arrow record-batch has (%d) columns but expected (%d)", nodeCount, len(asr.fields))
+	}
+
+	buffer := func(i int) []byte {
+		if i >= bufferCount {
+			log.Panicf("arrow record-batch has too few buffers")
+		}
+
+		position := buffersPosition + i*16
+		offset := binary.LittleEndian.Uint64(recordBatch.buffer[position:])
+		size := binary.LittleEndian.Uint64(recordBatch.buffer[position+8:])
+
+		if offset > uint64(len(body)) || size > uint64(len(body))-offset {
+			log.Panicf("arrow buffer out of range")
+		}
+
+		return body[offset : offset+size]
+	}
+
+	arb = arrowRecordBatch{
+		length: length,
+		times:  make([][]time.Time, 0),
+	}
+
+	bufferIndex := 0
+	for i, aft := range asr.fields {
+		nodePosition := nodesPosition + i*16
+		nodeLength := int(binary.LittleEndian.Uint64(recordBatch.buffer[nodePosition:]))
+		nullCount := int(binary.LittleEndian.Uint64(recordBatch.buffer[nodePosition+8:]))
+
+		if nodeLength != length {
+			log.Panicf("arrow column [%s] has (%d) rows but expected (%d)", aft.name, nodeLength, length)
+		}
+
+		validity := buffer(bufferIndex)
+		bufferIndex++
+
+		if nullCount > 0 && len(validity) < (length+7)/8 {
+			log.Panicf("arrow column [%s] validity bitmap too small", aft.name)
+		}
+
+		if aft.typeType == arrowTypeTimestamp {
+			if nullCount > 0 {
+				log.Panicf("arrow column [%s] has null times", aft.name)
+			}
+
+			data := buffer(bufferIndex)
+			bufferIndex++
+
+			if len(data) < length*8 {
+				log.Panicf("arrow column [%s] data too small", aft.name)
+			}
+
+			times := make([]time.Time, length)
+			for j := range times {
+				value := int64(binary.LittleEndian.Uint64(data[j*8:]))
+
+				switch aft.timeUnit {
+				case arrowTimeUnitSecond:
+					times[j] = time.Unix(value, 0).UTC()
+				case arrowTimeUnitMillisecond:
+					times[j] = time.Unix(value/1e3, value%1e3*1e6).UTC()
+				case arrowTimeUnitMicrosecond:
+					times[j] = time.Unix(value/1e6, value%1e6*1e3).UTC()
+				case arrowTimeUnitNanosecond:
+					times[j] = time.Unix(0, value).UTC()
+				default:
+					log.Panicf("arrow time-unit (%d) not valid", aft.timeUnit)
+				}
+			}
+
+			arb.times = append(arb.times, times)
+		} else {
+			offsets := buffer(bufferIndex)
+			data := buffer(bufferIndex + 1)
+			bufferIndex += 2
+
+			if len(offsets) < (length+1)*4 {
+				log.Panicf("arrow column [%s] offsets too small", aft.name)
+			}
+
+			arb.items = make([][]byte, length)
+			arb.isValid = make([]bool, length)
+
+			for j := 0; j < length; j++ {
+				arb.isValid[j] = nullCount == 0 || arrowBitIsSet(validity, j)
+
+				start := binary.LittleEndian.Uint32(offsets[j*4:])
+				end := binary.LittleEndian.Uint32(offsets[(j+1)*4:])
+				if start > end || end > uint32(len(data)) {
+					log.Panicf("arrow column [%s] offsets not valid", aft.name)
+				}
+
+				arb.items[j] = data[start:end]
+			}
+		}
+	}
+
+	return arb, nil
+}
+
+func (arb arrowRecordBatch) decodeItems(i int, codec JsonItemCodec) (items []interface{}, err error) {
+	if arb.isValid[i] == false {
+		return []interface{}{}, nil
+	}
+
+	return decodeArrowItems(arb.items[i], codec)
+}
+
+// ReadTimeSliceArrow reads a slice written by `WriteTimeSliceArrow`. Rows that
+// are not sorted are sorted.
+func ReadTimeSliceArrow(r io.Reader, codec JsonItemCodec) (ts TimeSlice, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	asr := &arrowStreamReader{br: bufio.NewReader(r)}
+
+	err = asr.readSchema([]string{"time", "items"})
+	log.PanicIf(err)
+
+	ts = make(TimeSlice, 0)
+	isSorted := true
+
+	for {
+		arb, err := asr.readRecordBatch()
+		if err == io.EOF {
+			break
+		}
+
+		log.PanicIf(err)
+
+		for i := 0; i < arb.length; i++ {
+			items, err := arb.decodeItems(i, codec)
+			log.PanicIf(err)
+
+			t := arb.times[0][i]
+			if len(ts) > 0 && ts[len(ts)-1].Time.Before(t) == false {
+				isSorted = false
+			}
+
+			ts = append(ts, TimeEntry{Time: t, Items: items})
+		}
+	}
+
+	if isSorted == false {
+		ts = sortAndCombineTimeSlice(ts)
+	}
+
+	return ts, nil
+}
+
+// ReadTimeIntervalSliceArrow reads a slice written by
+// `WriteTimeIntervalSliceArrow`. Rows that are not sorted are sorted.
+func ReadTimeIntervalSliceArrow(r io.Reader, codec JsonItemCodec) (tis TimeIntervalSlice, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	asr := &arrowStreamReader{br: bufio.NewReader(r)}
+
+	err = asr.readSchema([]string{"from", "to", "items"})
+	log.PanicIf(err)
+
+	tis = make(TimeIntervalSlice, 0)
+	isSorted := true
+
+	for {
+		arb, err := asr.readRecordBatch()
+		if err == io.EOF {
+			break
+		}
+
+		log.PanicIf(err)
+
+		for i := 0; i < arb.length; i++ {
+			items, err := arb.decodeItems(i, codec)
+			log.PanicIf(err)
+
+			ti := TimeInterval{
+				From:  arb.times[0][i],
+				To:    arb.times[1][i],
+				Items: items,
+			}
+
+			if ti.From.Before(ti.To) == false {
+				log.Panicf("interval is invalid: [%s] - [%s]", ti.From, ti.To)
+			}
+
+			if len(tis) > 0 && compareIntervals(tis[len(tis)-1], ti) >= 0 {
+				isSorted = false
+			}
+
+			tis = append(tis, ti)
+		}
+	}
+
+	if isSorted == false {
+		tis = sortAndCombineTimeIntervalSlice(tis)
+	}
+
+	return tis, nil
+}
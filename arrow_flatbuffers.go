@@ -0,0 +1,370 @@
+package timeindex
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// A minimal FlatBuffers encoder and decoder, enough to read and write Arrow
+// IPC metadata without depending on the FlatBuffers or Arrow libraries.
+//
+// The encoder lays objects out front-to-back: every table is preceded by its
+// vtable and is followed by the objects that it refers to, so every offset
+// points forward (as FlatBuffers requires). Scalars are aligned to their size
+// relative to the start of the buffer.
+
+// fbObject is one of *fbTable, fbString, *fbStructVector, or fbTableVector.
+type fbObject interface{}
+
+type fbString string
+
+// fbStructVector is a vector of fixed-size structs whose encoded (little-
+// endian) elements are concatenated in `data`.
+type fbStructVector struct {
+	elementAlignment int
+	count            int
+	data             []byte
+}
+
+type fbTableVector []*fbTable
+
+type fbField struct {
+	id int
+
+	// scalar is the little-endian encoding of a scalar field. Its length is
+	// also its alignment.
+	scalar []byte
+
+	// child is set for a field that refers to another object.
+	child fbObject
+}
+
+type fbTable struct {
+	fields []fbField
+}
+
+func newFbTable() *fbTable {
+	return &fbTable{
+		fields: make([]fbField, 0),
+	}
+}
+
+func (t *fbTable) addUint8(id int, value uint8) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, scalar: []byte{value}})
+	return t
+}
+
+func (t *fbTable) addInt16(id int, value int16) *fbTable {
+	scalar := make([]byte, 2)
+	binary.LittleEndian.PutUint16(scalar, uint16(value))
+
+	t.fields = append(t.fields, fbField{id: id, scalar: scalar})
+	return t
+}
+
+func (t *fbTable) addInt64(id int, value int64) *fbTable {
+	scalar := make([]byte, 8)
+	binary.LittleEndian.PutUint64(scalar, uint64(value))
+
+	t.fields = append(t.fields, fbField{id: id, scalar: scalar})
+	return t
+}
+
+func (t *fbTable) addChild(id int, child fbObject) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, child: child})
+	return t
+}
+
+type fbBuilder struct {
+	buffer []byte
+}
+
+func (b *fbBuilder) pad(alignment int) {
+	for len(b.buffer)%alignment != 0 {
+		b.buffer = append(b.buffer, 0)
+	}
+}
+
+func (b *fbBuilder) putUint32(position int, value uint32) {
+	binary.LittleEndian.PutUint32(b.buffer[position:], value)
+}
+
+func (b *fbBuilder) appendUint32(value uint32) {
+	encoded := make([]byte, 4)
+	binary.LittleEndian.PutUint32(encoded, value)
+
+	b.buffer = append(b.buffer, encoded...)
+}
+
+// finishFlatbuffer encodes a buffer with the given root table.
+func finishFlatbuffer(root *fbTable) []byte {
+	b := &fbBuilder{
+		buffer: make([]byte, 4),
+	}
+
+	position := b.write(root)
+	b.putUint32(0, uint32(position))
+
+	return b.buffer
+}
+
+// write encodes the object and returns the position that references to it
+// point to.
+func (b *fbBuilder) write(object fbObject) int {
+	switch o := object.(type) {
+	case *fbTable:
+		return b.writeTable(o)
+	case fbString:
+		b.pad(4)
+		position := len(b.buffer)
+
+		b.appendUint32(uint32(len(o)))
+		b.buffer = append(b.buffer, o...)
+		b.buffer = append(b.buffer, 0)
+
+		return position
+	case *fbStructVector:
+		// The elements following the length must be aligned.
+		b.pad(4)
+		for (len(b.buffer)+4)%o.elementAlignment != 0 {
+			b.buffer = append(b.buffer, 0, 0, 0, 0)
+		}
+
+		position := len(b.buffer)
+
+		b.appendUint32(uint32(o.count))
+		b.buffer = append(b.buffer, o.data...)
+
+		return position
+	case fbTableVector:
+		b.pad(4)
+		position := len(b.buffer)
+
+		b.appendUint32(uint32(len(o)))
+
+		slots := make([]int, len(o))
+		for i := range o {
+			slots[i] = len(b.buffer)
+			b.appendUint32(0)
+		}
+
+		for i, table := range o {
+			tablePosition := b.write(table)
+			b.putUint32(slots[i], uint32(tablePosition-slots[i]))
+		}
+
+		return position
+	}
+
+	panic(fmt.Errorf("flatbuffer object not valid: %v", object))
+}
+
+func (b *fbBuilder) writeTable(t *fbTable) int {
+	// Lay out the fields after the soffset, largest first so that they're
+	// naturally aligned as long as the table is aligned to the largest.
+
+	fields := make([]fbField, len(t.fields))
+	copy(fields, t.fields)
+
+	size := func(f fbField) int {
+		if f.child != nil {
+			return 4
+		}
+
+		return len(f.scalar)
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		return size(fields[i]) > size(fields[j])
+	})
+
+	alignment := 4
+	maxId := -1
+	for _, f := range fields {
+		if size(f) > alignment {
+			alignment = size(f)
+		}
+
+		if f.id > maxId {
+			maxId = f.id
+		}
+	}
+
+	// The largest fields are placed first, but they must still be aligned
+	// after the 4-byte soffset.
+	inlineOffsets := make([]int, len(fields))
+	inlineSize := 4
+	for i, f := range fields {
+		for inlineSize%size(f) != 0 {
+			inlineSize++
+		}
+
+		inlineOffsets[i] = inlineSize
+		inlineSize += size(f)
+	}
+
+	// Write the vtable.
+
+	b.pad(2)
+	vtablePosition := len(b.buffer)
+
+	vtable := make([]byte, 4+2*(maxId+1))
+	binary.LittleEndian.PutUint16(vtable[0:], uint16(len(vtable)))
+	binary.LittleEndian.PutUint16(vtable[2:], uint16(inlineSize))
+
+	for i, f := range fields {
+		binary.LittleEndian.PutUint16(vtable[4+2*f.id:], uint16(inlineOffsets[i]))
+	}
+
+	b.buffer = append(b.buffer, vtable...)
+
+	// Write the table.
+
+	b.pad(alignment)
+	tablePosition := len(b.buffer)
+
+	b.buffer = append(b.buffer, make([]byte, inlineSize)...)
+	b.putUint32(tablePosition, uint32(tablePosition-vtablePosition))
+
+	for i, f := range fields {
+		if f.child == nil {
+			copy(b.buffer[tablePosition+inlineOffsets[i]:], f.scalar)
+		}
+	}
+
+	// Write the children and point the fields at them.
+
+	for i, f := range fields {
+		if f.child != nil {
+			slot := tablePosition + inlineOffsets[i]
+			childPosition := b.write(f.child)
+			b.putUint32(slot, uint32(childPosition-slot))
+		}
+	}
+
+	return tablePosition
+}
+
+// fbTableReader reads a table. Out-of-range reads panic; the callers recover.
+type fbTableReader struct {
+	buffer   []byte
+	position int
+}
+
+func readFlatbufferRoot(buffer []byte) fbTableReader {
+	return fbTableReader{
+		buffer:   buffer,
+		position: int(binary.LittleEndian.Uint32(buffer)),
+	}
+}
+
+func (t fbTableReader) uint32At(position int) int {
+	value := binary.LittleEndian.Uint32(t.buffer[position:])
+	if value > uint32(len(t.buffer)) {
+		panic(fmt.Errorf("flatbuffer offset out of range"))
+	}
+
+	return int(value)
+}
+
+// fieldPosition returns the position of the field's value or (-1) if the
+// field is absent.
+func (t fbTableReader) fieldPosition(id int) int {
+	soffset := int32(binary.LittleEndian.Uint32(t.buffer[t.position:]))
+	vtablePosition := t.position - int(soffset)
+
+	vtableSize := int(binary.LittleEndian.Uint16(t.buffer[vtablePosition:]))
+	if 4+2*id >= vtableSize {
+		return -1
+	}
+
+	offset := int(binary.LittleEndian.Uint16(t.buffer[vtablePosition+4+2*id:]))
+	if offset == 0 {
+		return -1
+	}
+
+	return t.position + offset
+}
+
+func (t fbTableReader) uint8(id int, defaultValue uint8) uint8 {
+	position := t.fieldPosition(id)
+	if position == -1 {
+		return defaultValue
+	}
+
+	return t.buffer[position]
+}
+
+func (t fbTableReader) int16(id int, defaultValue int16) int16 {
+	position := t.fieldPosition(id)
+	if position == -1 {
+		return defaultValue
+	}
+
+	return int16(binary.LittleEndian.Uint16(t.buffer[position:]))
+}
+
+func (t fbTableReader) int64(id int, defaultValue int64) int64 {
+	position := t.fieldPosition(id)
+	if position == -1 {
+		return defaultValue
+	}
+
+	return int64(binary.LittleEndian.Uint64(t.buffer[position:]))
+}
+
+// reference returns the position that a reference field points to.
+func (t fbTableReader) reference(id int) (position int, found bool) {
+	position = t.fieldPosition(id)
+	if position == -1 {
+		return 0, false
+	}
+
+	return position + t.uint32At(position), true
+}
+
+func (t fbTableReader) table(id int) (child fbTableReader, found bool) {
+	position, found := t.reference(id)
+	if found == false {
+		return child, false
+	}
+
+	child = fbTableReader{
+		buffer:   t.buffer,
+		position: position,
+	}
+
+	return child, true
+}
+
+func (t fbTableReader) string(id int) string {
+	position, found := t.reference(id)
+	if found == false {
+		return ""
+	}
+
+	length := t.uint32At(position)
+
+	return string(t.buffer[position+4 : position+4+length])
+}
+
+// vector returns the position of the first element and the number of
+// elements.
+func (t fbTableReader) vector(id int) (elementsPosition int, count int) {
+	position, found := t.reference(id)
+	if found == false {
+		return 0, 0
+	}
+
+	return position + 4, t.uint32At(position)
+}
+
+func (t fbTableReader) tableVectorElement(elementsPosition int, i int) fbTableReader {
+	slot := elementsPosition + i*4
+
+	return fbTableReader{
+		buffer:   t.buffer,
+		position: slot + t.uint32At(slot),
+	}
+}
@@ -0,0 +1,233 @@
+package timeindex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestTimeSliceArrow_RoundTrip(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339Nano, "2016-12-02T08:05:44.123456789Z")
+	log.PanicIf(err)
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(time1, "abc")
+	ts = ts.Add(time1, float64(12))
+	ts = ts.Add(time1.Add(time.Hour), nil)
+	ts = append(ts, TimeEntry{Time: time1.Add(time.Hour * 2), Items: []interface{}{}})
+
+	b := new(bytes.Buffer)
+
+	err = WriteTimeSliceArrow(b, ts, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	recovered, err := ReadTimeSliceArrow(b, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	if len(recovered) != len(ts) {
+		t.Fatalf("Entry count not correct: (%d)", len(recovered))
+	}
+
+	for i, te := range ts {
+		if recovered[i].Time.Equal(te.Time) == false || reflect.DeepEqual(recovered[i].Items, te.Items) == false {
+			t.Fatalf("Entry (%d) not correct: %v != %v", i, recovered[i], te)
+		}
+	}
+}
+
+func TestTimeSliceArrow_MultipleBatches(t *testing.T) {
+	start := getTestEpoch()
+
+	ts := make(TimeSlice, arrowBatchSize+10)
+	for i := range ts {
+		ts[i] = TimeEntry{
+			Time:  start.Add(time.Duration(i) * time.Millisecond),
+			Items: []interface{}{float64(i)},
+		}
+	}
+
+	b := new(bytes.Buffer)
+
+	err := WriteTimeSliceArrow(b, ts, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	recovered, err := ReadTimeSliceArrow(b, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	if len(recovered) != len(ts) {
+		t.Fatalf("Entry count not correct: (%d)", len(recovered))
+	}
+
+	last := recovered[len(recovered)-1]
+	if last.Time.Equal(ts[len(ts)-1].Time) == false || last.Items[0] != float64(len(ts)-1) {
+		t.Fatalf("Last entry not correct: %v", last)
+	}
+}
+
+func TestTimeIntervalSliceArrow_RoundTrip(t *testing.T) {
+	left1, err := time.Parse(time.RFC3339, "2016-12-03T07:00:00Z")
+	log.PanicIf(err)
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(left1, left1.Add(time.Hour*2), "a")
+	tis = tis.Add(left1, left1.Add(time.Hour), "b")
+	tis = tis.Add(left1.Add(time.Minute), left1.Add(time.Hour), nil)
+
+	b := new(bytes.Buffer)
+
+	err = WriteTimeIntervalSliceArrow(b, tis, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	recovered, err := ReadTimeIntervalSliceArrow(b, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	if len(recovered) != len(tis) {
+		t.Fatalf("Interval count not correct: (%d)", len(recovered))
+	}
+
+	for i, ti := range tis {
+		if recovered[i].From.Equal(ti.From) == false || recovered[i].To.Equal(ti.To) == false || reflect.DeepEqual(recovered[i].Items, ti.Items) == false {
+			t.Fatalf("Interval (%d) not correct: %v != %v", i, recovered[i], ti)
+		}
+	}
+}
+
+// The streams under testdata were written by the Arrow Go library (v12), with
+// millisecond and second timestamps.
+func TestReadTimeSliceArrow_Foreign(t *testing.T) {
+	f, err := os.Open(path.Join("testdata", "time_slice_ms.arrow"))
+	log.PanicIf(err)
+
+	defer f.Close()
+
+	ts, err := ReadTimeSliceArrow(f, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	epoch := getTestEpoch()
+
+	expected := TimeSlice{
+		{Time: epoch.Add(time.Millisecond * 1500), Items: []interface{}{"abc", float64(12)}},
+		{Time: epoch.Add(time.Hour), Items: []interface{}{}},
+		{Time: epoch.Add(time.Hour * 2), Items: []interface{}{"def"}},
+	}
+
+	if len(ts) != len(expected) {
+		t.Fatalf("Entry count not correct: (%d)", len(ts))
+	}
+
+	for i, te := range expected {
+		if ts[i].Time.Equal(te.Time) == false || reflect.DeepEqual(ts[i].Items, te.Items) == false {
+			t.Fatalf("Entry (%d) not correct: %v != %v", i, ts[i], te)
+		}
+	}
+}
+
+func TestReadTimeIntervalSliceArrow_Foreign(t *testing.T) {
+	f, err := os.Open(path.Join("testdata", "time_interval_slice_s.arrow"))
+	log.PanicIf(err)
+
+	defer f.Close()
+
+	tis, err := ReadTimeIntervalSliceArrow(f, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	epoch := getTestEpoch()
+
+	expected := TimeIntervalSlice{
+		{From: epoch, To: epoch.Add(time.Hour), Items: []interface{}{"b"}},
+		{From: epoch, To: epoch.Add(time.Hour * 2), Items: []interface{}{"a"}},
+	}
+
+	if len(tis) != len(expected) {
+		t.Fatalf("Interval count not correct: (%d)", len(tis))
+	}
+
+	for i, ti := range expected {
+		if tis[i].From.Equal(ti.From) == false || tis[i].To.Equal(ti.To) == false || reflect.DeepEqual(tis[i].Items, ti.Items) == false {
+			t.Fatalf("Interval (%d) not correct: %v != %v", i, tis[i], ti)
+		}
+	}
+}
+
+func TestReadTimeSliceArrow_WrongSchema(t *testing.T) {
+	left1, err := time.Parse(time.RFC3339, "2016-12-03T07:00:00Z")
+	log.PanicIf(err)
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(left1, left1.Add(time.Hour), "a")
+
+	b := new(bytes.Buffer)
+
+	err = WriteTimeIntervalSliceArrow(b, tis, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	_, err = ReadTimeSliceArrow(b, DefaultJsonItemCodec)
+	if err == nil {
+		t.Fatalf("Expected error for interval schema.")
+	}
+}
+
+func TestReadTimeSliceArrow_Truncated(t *testing.T) {
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(getTestEpoch(), "abc")
+
+	b := new(bytes.Buffer)
+
+	err := WriteTimeSliceArrow(b, ts, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	data := b.Bytes()
+
+	_, err = ReadTimeSliceArrow(bytes.NewReader(data[:len(data)-20]), DefaultJsonItemCodec)
+	if err == nil {
+		t.Fatalf("Expected error for truncated stream.")
+	}
+}
+
+func TestFlatbuffer_RoundTrip(t *testing.T) {
+	nodes := &fbStructVector{
+		elementAlignment: 8,
+		count:            2,
+		data:             make([]byte, 32),
+	}
+
+	binary.LittleEndian.PutUint64(nodes.data[16:], 99)
+
+	child := newFbTable().
+		addChild(0, fbString("child"))
+
+	root := newFbTable().
+		addUint8(0, 7).
+		addInt64(1, -5).
+		addChild(2, fbString("name")).
+		addChild(3, nodes).
+		addChild(4, fbTableVector{child, child}).
+		addInt16(6, 300)
+
+	data := finishFlatbuffer(root)
+	r := readFlatbufferRoot(data)
+
+	if r.uint8(0, 0) != 7 || r.int64(1, 0) != -5 || r.int16(6, 0) != 300 {
+		t.Fatalf("Scalars not correct.")
+	} else if r.int16(5, 42) != 42 || r.int16(20, 43) != 43 {
+		t.Fatalf("Defaults not correct.")
+	} else if r.string(2) != "name" {
+		t.Fatalf("String not correct: [%s]", r.string(2))
+	}
+
+	elementsPosition, count := r.vector(3)
+	if count != 2 || elementsPosition%8 != 0 || binary.LittleEndian.Uint64(data[elementsPosition+16:]) != 99 {
+		t.Fatalf("Struct vector not correct: (%d) (%d)", elementsPosition, count)
+	}
+
+	elementsPosition, count = r.vector(4)
+	if count != 2 || r.tableVectorElement(elementsPosition, 1).string(0) != "child" {
+		t.Fatalf("Table vector not correct.")
+	}
+}
@@ -0,0 +1,124 @@
+package timeindex
+
+import (
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// AsOfDirection is which right entry `AsOfJoin` matches to a left entry.
+type AsOfDirection int
+
+const (
+	// AsOfBackward matches the last right entry at or before the left entry.
+	AsOfBackward AsOfDirection = iota
+
+	// AsOfForward matches the first right entry at or after the left entry.
+	AsOfForward
+
+	// AsOfNearest matches the closest right entry (the earlier one on a tie).
+	AsOfNearest
+)
+
+// AsOfUnlimited is the `AsOfJoin` tolerance that matches entries at any
+// distance. Any negative tolerance has the same effect.
+const AsOfUnlimited time.Duration = -1
+
+// AsOfJoin calls the callback for every entry of `left` with the entry of
+// `right` that it matches in the given direction. `found` is false (and
+// `match` is empty) if there is no such entry within `tolerance`. A zero
+// tolerance only matches equal times and a negative one (`AsOfUnlimited`) has
+// no limit. Both slices must be sorted; they are merged in linear time.
+func AsOfJoin(
+	left, right TimeSlice, tolerance time.Duration, direction AsOfDirection,
+	cb func(te TimeEntry, match TimeEntry, found bool) error,
+) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if direction != AsOfBackward && direction != AsOfForward && direction != AsOfNearest {
+		log.Panicf("as-of direction (%d) not valid", direction)
+	}
+
+	// `j` is the first right entry after the current left entry.
+	j := 0
+
+	for _, te := range left {
+		for j < len(right) && right[j].Time.After(te.Time) == false {
+			j++
+		}
+
+		// The candidates on either side. An exact match is always `before`.
+		before, after := j-1, j
+		if after >= len(right) {
+			after = -1
+		}
+
+		candidate := -1
+
+		switch direction {
+		case AsOfBackward:
+			candidate = before
+		case AsOfForward:
+			if before >= 0 && right[before].Time.Equal(te.Time) == true {
+				candidate = before
+			} else {
+				candidate = after
+			}
+		case AsOfNearest:
+			candidate = before
+			if after != -1 {
+				afterDistance := right[after].Time.Sub(te.Time)
+				if before == -1 || afterDistance < te.Time.Sub(right[before].Time) {
+					candidate = after
+				}
+			}
+		}
+
+		if candidate != -1 && tolerance >= 0 {
+			if AbsoluteDistance(te.Time, right[candidate].Time) > tolerance {
+				candidate = -1
+			}
+		}
+
+		if candidate == -1 {
+			err := cb(te, TimeEntry{}, false)
+			log.PanicIf(err)
+
+			continue
+		}
+
+		err := cb(te, right[candidate], true)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// AsOfMatch is one result of `AsOfJoinAndReturn`.
+type AsOfMatch struct {
+	Left  TimeEntry
+	Right TimeEntry
+	Found bool
+}
+
+// AsOfJoinAndReturn is like `AsOfJoin` but returns the matches.
+func AsOfJoinAndReturn(
+	left, right TimeSlice, tolerance time.Duration, direction AsOfDirection,
+) (matches []AsOfMatch, err error) {
+	matches = make([]AsOfMatch, 0, len(left))
+
+	cb := func(te TimeEntry, match TimeEntry, found bool) error {
+		matches = append(matches, AsOfMatch{Left: te, Right: match, Found: found})
+		return nil
+	}
+
+	if err := AsOfJoin(left, right, tolerance, direction, cb); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,111 @@
+package timeindex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getAsOfTestSlices() (trades, quotes TimeSlice) {
+	trades = make(TimeSlice, 0)
+	for _, seconds := range []int{1, 5, 10, 12, 30} {
+		trades = trades.Add(getTestSecond(seconds), seconds)
+	}
+
+	quotes = make(TimeSlice, 0)
+	for _, seconds := range []int{2, 5, 8, 14} {
+		quotes = quotes.Add(getTestSecond(seconds), -seconds)
+	}
+
+	return trades, quotes
+}
+
+// matchedSeconds returns the offset of the matched quote for every trade, or
+// (-1) if there was no match.
+func matchedSeconds(matches []AsOfMatch) []int {
+	seconds := make([]int, len(matches))
+	for i, am := range matches {
+		if am.Found == false {
+			seconds[i] = -1
+		} else {
+			seconds[i] = -am.Right.Items[0].(int)
+		}
+	}
+
+	return seconds
+}
+
+func TestAsOfJoin(t *testing.T) {
+	trades, quotes := getAsOfTestSlices()
+
+	cases := []struct {
+		direction AsOfDirection
+		tolerance time.Duration
+		expected  []int
+	}{
+		{AsOfBackward, AsOfUnlimited, []int{-1, 5, 8, 8, 14}},
+		{AsOfBackward, time.Second * 3, []int{-1, 5, 8, -1, -1}},
+		{AsOfBackward, 0, []int{-1, 5, -1, -1, -1}},
+		{AsOfBackward, -time.Second, []int{-1, 5, 8, 8, 14}},
+		{AsOfForward, AsOfUnlimited, []int{2, 5, 14, 14, -1}},
+		{AsOfForward, time.Second * 3, []int{2, 5, -1, 14, -1}},
+		{AsOfForward, 0, []int{-1, 5, -1, -1, -1}},
+		{AsOfNearest, AsOfUnlimited, []int{2, 5, 8, 14, 14}},
+		{AsOfNearest, time.Second, []int{2, 5, -1, -1, -1}},
+		{AsOfNearest, 0, []int{-1, 5, -1, -1, -1}},
+	}
+
+	for i, c := range cases {
+		matches, err := AsOfJoinAndReturn(trades, quotes, c.tolerance, c.direction)
+		log.PanicIf(err)
+
+		if len(matches) != len(trades) {
+			t.Fatalf("Case (%d) match count not correct: (%d)", i, len(matches))
+		}
+
+		seconds := matchedSeconds(matches)
+		for j, expected := range c.expected {
+			if seconds[j] != expected {
+				t.Fatalf("Case (%d) not correct: %v", i, seconds)
+			} else if matches[j].Left.Time.Equal(trades[j].Time) == false {
+				t.Fatalf("Case (%d) left entry (%d) not correct.", i, j)
+			}
+		}
+	}
+}
+
+func TestAsOfJoin_NearestTie(t *testing.T) {
+	_, quotes := getAsOfTestSlices()
+
+	left := make(TimeSlice, 0)
+	left = left.Add(getTestSecond(11), nil)
+
+	matches, err := AsOfJoinAndReturn(left, quotes, AsOfUnlimited, AsOfNearest)
+	log.PanicIf(err)
+
+	if seconds := matchedSeconds(matches); seconds[0] != 8 {
+		t.Fatalf("Expected the earlier quote on a tie: %v", seconds)
+	}
+}
+
+func TestAsOfJoin_EmptyRight(t *testing.T) {
+	trades, _ := getAsOfTestSlices()
+
+	matches, err := AsOfJoinAndReturn(trades, TimeSlice{}, AsOfUnlimited, AsOfNearest)
+	log.PanicIf(err)
+
+	for _, am := range matches {
+		if am.Found == true {
+			t.Fatalf("Expected no matches.")
+		}
+	}
+}
+
+func TestAsOfJoin_Invalid(t *testing.T) {
+	trades, quotes := getAsOfTestSlices()
+
+	if _, err := AsOfJoinAndReturn(trades, quotes, 0, AsOfDirection(99)); err == nil {
+		t.Fatalf("Expected error for unknown direction.")
+	}
+}
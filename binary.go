@@ -0,0 +1,427 @@
+package timeindex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// The binary encoding only stores the times. The items are not encoded and
+// the decoded entries will have no items. Times are decoded as UTC.
+//
+// Layout:
+//
+//   magic (4 bytes) | version (1 byte) | kind (1 byte) | count (uvarint) |
+//   bit-stream | CRC32 of everything before it (4 bytes, big-endian)
+//
+// The bit-stream stores the first time (as nanoseconds since the epoch)
+// verbatim and then the delta-of-delta of every time after it (Gorilla-
+// style). Series with a regular cadence will cost one bit per time. Intervals
+// store their start-times the same way, each followed by the delta of its
+// duration relative to the previous interval's duration.
+
+const (
+	binaryVersion = 1
+
+	binaryKindTimeSlice         = 1
+	binaryKindTimeIntervalSlice = 2
+)
+
+var (
+	binaryMagic = []byte{'T', 'I', 'X', 'B'}
+)
+
+var (
+	ErrBinaryChecksum = errors.New("checksum mismatch")
+	ErrTimeOutOfRange = errors.New("time can not be represented as nanoseconds since the epoch")
+	ErrBinaryTrailing = errors.New("trailing data after the encoded times")
+)
+
+type bitWriter struct {
+	buffer []byte
+
+	// used is the number of bits used in the last byte.
+	used uint
+}
+
+func (bw *bitWriter) writeBits(value uint64, count uint) {
+	for count > 0 {
+		if bw.used == 0 || bw.used == 8 {
+			bw.buffer = append(bw.buffer, 0)
+			bw.used = 0
+		}
+
+		free := 8 - bw.used
+		n := free
+		if count < n {
+			n = count
+		}
+
+		bits := byte((value >> (count - n)) & (1<<n - 1))
+		bw.buffer[len(bw.buffer)-1] |= bits << (free - n)
+
+		bw.used += n
+		count -= n
+	}
+}
+
+type bitReader struct {
+	buffer []byte
+	offset uint
+}
+
+func (br *bitReader) readBits(count uint) (value uint64, err error) {
+	if br.offset+count > uint(len(br.buffer))*8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	for count > 0 {
+		current := br.buffer[br.offset/8]
+		used := br.offset % 8
+		free := 8 - used
+
+		n := free
+		if count < n {
+			n = count
+		}
+
+		bits := (current >> (free - n)) & (1<<n - 1)
+		value = value<<n | uint64(bits)
+
+		br.offset += n
+		count -= n
+	}
+
+	return value, nil
+}
+
+// hasTrailingBytes returns true if there are whole bytes that haven't been
+// read. Only the padding of the last byte may be left.
+func (br *bitReader) hasTrailingBytes() bool {
+	return uint(len(br.buffer))*8-br.offset >= 8
+}
+
+// valueBuckets describes the prefix-coded widths used for deltas. A zero
+// value is written as a single "0" bit.
+var valueBuckets = []struct {
+	prefix     uint64
+	prefixBits uint
+	valueBits  uint
+}{
+	{prefix: 0x2, prefixBits: 2, valueBits: 7},
+	{prefix: 0x6, prefixBits: 3, valueBits: 9},
+	{prefix: 0xe, prefixBits: 4, valueBits: 12},
+	{prefix: 0x1e, prefixBits: 5, valueBits: 32},
+	{prefix: 0x1f, prefixBits: 5, valueBits: 64},
+}
+
+func (bw *bitWriter) writeValue(v int64) {
+	if v == 0 {
+		bw.writeBits(0, 1)
+		return
+	}
+
+	for _, bucket := range valueBuckets {
+		if bucket.valueBits < 64 {
+			limit := int64(1) << (bucket.valueBits - 1)
+			if v < -limit || v >= limit {
+				continue
+			}
+		}
+
+		bw.writeBits(bucket.prefix, bucket.prefixBits)
+		bw.writeBits(uint64(v)&(1<<bucket.valueBits-1), bucket.valueBits)
+
+		return
+	}
+}
+
+func (br *bitReader) readValue() (v int64, err error) {
+	// Count the leading one-bits of the prefix.
+
+	ones := uint(0)
+	for ; ones < 5; ones++ {
+		bit, err := br.readBits(1)
+		if err != nil {
+			return 0, err
+		}
+
+		if bit == 0 {
+			break
+		}
+	}
+
+	if ones == 0 {
+		return 0, nil
+	}
+
+	bucket := valueBuckets[ones-1]
+
+	raw, err := br.readBits(bucket.valueBits)
+	if err != nil {
+		return 0, err
+	}
+
+	// Sign-extend.
+	shift := 64 - bucket.valueBits
+	v = int64(raw<<shift) >> shift
+
+	return v, nil
+}
+
+func toUnixNano(t time.Time) (n int64, err error) {
+	n = t.UnixNano()
+	if time.Unix(0, n).Equal(t) == false {
+		return 0, ErrTimeOutOfRange
+	}
+
+	return n, nil
+}
+
+// timeStreamEncoder writes the delta-of-delta of successive times.
+type timeStreamEncoder struct {
+	bw        *bitWriter
+	count     int
+	previous  int64
+	lastDelta int64
+}
+
+func (tse *timeStreamEncoder) write(t time.Time) (err error) {
+	n, err := toUnixNano(t)
+	if err != nil {
+		return err
+	}
+
+	if tse.count == 0 {
+		tse.bw.writeBits(uint64(n), 64)
+	} else {
+		delta := n - tse.previous
+		tse.bw.writeValue(delta - tse.lastDelta)
+		tse.lastDelta = delta
+	}
+
+	tse.previous = n
+	tse.count++
+
+	return nil
+}
+
+type timeStreamDecoder struct {
+	br        *bitReader
+	count     int
+	previous  int64
+	lastDelta int64
+}
+
+func (tsd *timeStreamDecoder) read() (t time.Time, err error) {
+	if tsd.count == 0 {
+		raw, err := tsd.br.readBits(64)
+		if err != nil {
+			return t, err
+		}
+
+		tsd.previous = int64(raw)
+	} else {
+		dod, err := tsd.br.readValue()
+		if err != nil {
+			return t, err
+		}
+
+		tsd.lastDelta += dod
+		tsd.previous += tsd.lastDelta
+	}
+
+	tsd.count++
+
+	return time.Unix(0, tsd.previous).UTC(), nil
+}
+
+func writeBinaryHeader(b *bytes.Buffer, kind byte, count int) {
+	b.Write(binaryMagic)
+	b.WriteByte(binaryVersion)
+	b.WriteByte(kind)
+
+	encodedCount := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(encodedCount, uint64(count))
+	b.Write(encodedCount[:n])
+}
+
+func appendBinaryChecksum(b *bytes.Buffer) []byte {
+	checksum := make([]byte, 4)
+	binary.BigEndian.PutUint32(checksum, crc32.ChecksumIEEE(b.Bytes()))
+	b.Write(checksum)
+
+	return b.Bytes()
+}
+
+// readBinaryHeader validates the envelope and returns the count and the bit-
+// stream.
+func readBinaryHeader(data []byte, kind byte) (count int, stream []byte, err error) {
+	if len(data) < len(binaryMagic)+2+1+4 {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+
+	body := data[:len(data)-4]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(data[len(data)-4:]) {
+		return 0, nil, ErrBinaryChecksum
+	}
+
+	if bytes.Equal(body[:4], binaryMagic) == false {
+		return 0, nil, fmt.Errorf("binary encoding not recognized")
+	} else if body[4] != binaryVersion {
+		return 0, nil, fmt.Errorf("binary encoding version (%d) not supported", body[4])
+	} else if body[5] != kind {
+		return 0, nil, fmt.Errorf("binary encoding is of kind (%d) but expected (%d)", body[5], kind)
+	}
+
+	rawCount, n := binary.Uvarint(body[6:])
+	if n <= 0 {
+		return 0, nil, fmt.Errorf("binary encoding count not valid")
+	}
+
+	return int(rawCount), body[6+n:], nil
+}
+
+// MarshalBinary encodes the times of the slice. Items are not encoded.
+func (ts TimeSlice) MarshalBinary() (data []byte, err error) {
+	bw := &bitWriter{}
+	tse := &timeStreamEncoder{bw: bw}
+
+	for _, te := range ts {
+		if err := tse.write(te.Time); err != nil {
+			return nil, err
+		}
+	}
+
+	b := new(bytes.Buffer)
+
+	writeBinaryHeader(b, binaryKindTimeSlice, len(ts))
+	b.Write(bw.buffer)
+
+	return appendBinaryChecksum(b), nil
+}
+
+// UnmarshalBinary decodes times encoded by `MarshalBinary`. The entries will
+// have no items.
+func (ts *TimeSlice) UnmarshalBinary(data []byte) (err error) {
+	count, stream, err := readBinaryHeader(data, binaryKindTimeSlice)
+	if err != nil {
+		return err
+	}
+
+	// Every time after the first costs at least one bit.
+	if count > 0 && uint64(count-1) > uint64(len(stream))*8 {
+		return io.ErrUnexpectedEOF
+	}
+
+	br := &bitReader{buffer: stream}
+	tsd := &timeStreamDecoder{br: br}
+
+	decoded := make(TimeSlice, count)
+	for i := 0; i < count; i++ {
+		t, err := tsd.read()
+		if err != nil {
+			return err
+		}
+
+		decoded[i] = TimeEntry{
+			Time:  t,
+			Items: []interface{}{},
+		}
+	}
+
+	if br.hasTrailingBytes() == true {
+		return ErrBinaryTrailing
+	}
+
+	*ts = decoded
+
+	return nil
+}
+
+// MarshalBinary encodes the intervals of the slice. Items are not encoded.
+func (tis TimeIntervalSlice) MarshalBinary() (data []byte, err error) {
+	bw := &bitWriter{}
+	tse := &timeStreamEncoder{bw: bw}
+
+	lastDuration := int64(0)
+	for _, ti := range tis {
+		if err := tse.write(ti.From); err != nil {
+			return nil, err
+		}
+
+		from, err := toUnixNano(ti.From)
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := toUnixNano(ti.To)
+		if err != nil {
+			return nil, err
+		}
+
+		// Not `ti.To.Sub(ti.From)`, which saturates for intervals longer
+		// than about 292 years.
+		duration := to - from
+		bw.writeValue(duration - lastDuration)
+		lastDuration = duration
+	}
+
+	b := new(bytes.Buffer)
+
+	writeBinaryHeader(b, binaryKindTimeIntervalSlice, len(tis))
+	b.Write(bw.buffer)
+
+	return appendBinaryChecksum(b), nil
+}
+
+// UnmarshalBinary decodes intervals encoded by `MarshalBinary`. The intervals
+// will have no items.
+func (tis *TimeIntervalSlice) UnmarshalBinary(data []byte) (err error) {
+	count, stream, err := readBinaryHeader(data, binaryKindTimeIntervalSlice)
+	if err != nil {
+		return err
+	}
+
+	// Every interval costs at least two bits.
+	if uint64(count) > uint64(len(stream))*4 {
+		return io.ErrUnexpectedEOF
+	}
+
+	br := &bitReader{buffer: stream}
+	tsd := &timeStreamDecoder{br: br}
+
+	decoded := make(TimeIntervalSlice, count)
+	duration := int64(0)
+	for i := 0; i < count; i++ {
+		from, err := tsd.read()
+		if err != nil {
+			return err
+		}
+
+		delta, err := br.readValue()
+		if err != nil {
+			return err
+		}
+
+		duration += delta
+
+		decoded[i] = TimeInterval{
+			From:  from,
+			To:    time.Unix(0, from.UnixNano()+duration).UTC(),
+			Items: []interface{}{},
+		}
+	}
+
+	if br.hasTrailingBytes() == true {
+		return ErrBinaryTrailing
+	}
+
+	*tis = decoded
+
+	return nil
+}
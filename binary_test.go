@@ -0,0 +1,252 @@
+package timeindex
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestBitWriter_RoundTrip(t *testing.T) {
+	values := []int64{0, 1, -1, 63, -64, 64, 255, -256, 2047, -2048, 2048, 1 << 31, -(1 << 40), 0, 1<<63 - 1, -1 << 63}
+
+	bw := &bitWriter{}
+	for _, v := range values {
+		bw.writeValue(v)
+	}
+
+	br := &bitReader{buffer: bw.buffer}
+	for i, expected := range values {
+		actual, err := br.readValue()
+		log.PanicIf(err)
+
+		if actual != expected {
+			t.Fatalf("Value (%d) not correct: (%d) != (%d)", i, actual, expected)
+		}
+	}
+}
+
+func TestTimeSlice_BinaryRoundTrip(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339Nano, "2016-12-02T08:05:44.123456789Z")
+	log.PanicIf(err)
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(time1, "a")
+	ts = ts.Add(time1.Add(time.Second), nil)
+	ts = ts.Add(time1.Add(time.Second*2), nil)
+	ts = ts.Add(time1.Add(time.Second*2+time.Nanosecond), nil)
+	ts = ts.Add(time1.Add(time.Hour*24*365*50), nil)
+
+	data, err := ts.MarshalBinary()
+	log.PanicIf(err)
+
+	var recovered TimeSlice
+
+	err = recovered.UnmarshalBinary(data)
+	log.PanicIf(err)
+
+	if len(recovered) != len(ts) {
+		t.Fatalf("Entry count not correct: (%d)", len(recovered))
+	}
+
+	for i, te := range ts {
+		if recovered[i].Time.Equal(te.Time) == false {
+			t.Fatalf("Entry (%d) not correct: [%s] != [%s]", i, recovered[i].Time, te.Time)
+		} else if len(recovered[i].Items) != 0 {
+			t.Fatalf("Entry (%d) should not have items.", i)
+		}
+	}
+}
+
+func TestTimeSlice_MarshalBinary_RegularCadence(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339, "2016-12-02T08:00:00Z")
+	log.PanicIf(err)
+
+	ts := make(TimeSlice, 0)
+	for i := 0; i < 10000; i++ {
+		ts = append(ts, TimeEntry{Time: time1.Add(time.Minute * time.Duration(i))})
+	}
+
+	data, err := ts.MarshalBinary()
+	log.PanicIf(err)
+
+	// One bit per time after the first two, plus the envelope.
+	if len(data) > 10000/8+32 {
+		t.Fatalf("Encoding too large: (%d) bytes", len(data))
+	}
+
+	var recovered TimeSlice
+
+	err = recovered.UnmarshalBinary(data)
+	log.PanicIf(err)
+
+	if len(recovered) != len(ts) || recovered[9999].Time.Equal(ts[9999].Time) == false {
+		t.Fatalf("Round-trip not correct.")
+	}
+}
+
+func TestTimeSlice_MarshalBinary_Empty(t *testing.T) {
+	data, err := TimeSlice{}.MarshalBinary()
+	log.PanicIf(err)
+
+	var recovered TimeSlice
+
+	err = recovered.UnmarshalBinary(data)
+	log.PanicIf(err)
+
+	if len(recovered) != 0 {
+		t.Fatalf("Expected no entries.")
+	}
+}
+
+func TestTimeSlice_MarshalBinary_OutOfRange(t *testing.T) {
+	ts := TimeSlice{TimeEntry{Time: time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)}}
+
+	_, err := ts.MarshalBinary()
+	if err != ErrTimeOutOfRange {
+		t.Fatalf("Expected out-of-range error: %v", err)
+	}
+}
+
+func TestTimeSlice_UnmarshalBinary_Corrupt(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339, "2016-12-02T08:00:00Z")
+	log.PanicIf(err)
+
+	ts := TimeSlice{TimeEntry{Time: time1}, TimeEntry{Time: time1.Add(time.Second)}}
+
+	data, err := ts.MarshalBinary()
+	log.PanicIf(err)
+
+	data[len(data)-5] ^= 0xff
+
+	var recovered TimeSlice
+
+	err = recovered.UnmarshalBinary(data)
+	if err != ErrBinaryChecksum {
+		t.Fatalf("Expected checksum error: %v", err)
+	}
+
+	err = recovered.UnmarshalBinary(data[:3])
+	if err == nil {
+		t.Fatalf("Expected error for truncated data.")
+	}
+}
+
+func TestTimeSlice_UnmarshalBinary_WrongKind(t *testing.T) {
+	data, err := TimeIntervalSlice{}.MarshalBinary()
+	log.PanicIf(err)
+
+	var recovered TimeSlice
+
+	err = recovered.UnmarshalBinary(data)
+	if err == nil {
+		t.Fatalf("Expected error for wrong kind.")
+	}
+}
+
+func TestTimeIntervalSlice_BinaryRoundTrip(t *testing.T) {
+	left1, err := time.Parse(time.RFC3339, "2016-12-03T07:00:00Z")
+	log.PanicIf(err)
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(left1, left1.Add(time.Hour), "a")
+	tis = tis.Add(left1, left1.Add(time.Hour*2), nil)
+	tis = tis.Add(left1.Add(time.Minute), left1.Add(time.Minute*2), nil)
+	tis = tis.Add(left1.Add(time.Hour*24), left1.Add(time.Hour*25), nil)
+
+	data, err := tis.MarshalBinary()
+	log.PanicIf(err)
+
+	var recovered TimeIntervalSlice
+
+	err = recovered.UnmarshalBinary(data)
+	log.PanicIf(err)
+
+	if len(recovered) != len(tis) {
+		t.Fatalf("Interval count not correct: (%d)", len(recovered))
+	}
+
+	for i, ti := range tis {
+		if recovered[i].From.Equal(ti.From) == false || recovered[i].To.Equal(ti.To) == false {
+			t.Fatalf("Interval (%d) not correct: %v != %v", i, recovered[i], ti)
+		}
+	}
+}
+
+func TestTimeIntervalSlice_MarshalBinary_RegularCadence(t *testing.T) {
+	left1, err := time.Parse(time.RFC3339, "2016-12-03T07:00:00Z")
+	log.PanicIf(err)
+
+	tis := make(TimeIntervalSlice, 0)
+	for i := 0; i < 10000; i++ {
+		from := left1.Add(time.Hour * time.Duration(i))
+		tis = append(tis, TimeInterval{From: from, To: from.Add(time.Minute * 15)})
+	}
+
+	data, err := tis.MarshalBinary()
+	log.PanicIf(err)
+
+	// Two bits per interval, plus the first interval and the envelope.
+	if len(data) > 10000*2/8+64 {
+		t.Fatalf("Encoding too large: (%d) bytes", len(data))
+	}
+}
+
+func TestTimeIntervalSlice_BinaryRoundTrip_Wide(t *testing.T) {
+	// Longer than a `time.Duration` can hold.
+	from := time.Date(1700, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(from, to, nil)
+	tis = tis.Add(from.Add(time.Hour), from.Add(time.Hour*2), nil)
+
+	data, err := tis.MarshalBinary()
+	log.PanicIf(err)
+
+	var recovered TimeIntervalSlice
+
+	err = recovered.UnmarshalBinary(data)
+	log.PanicIf(err)
+
+	for i, ti := range tis {
+		if recovered[i].From.Equal(ti.From) == false || recovered[i].To.Equal(ti.To) == false {
+			t.Fatalf("Interval (%d) not correct: %v != %v", i, recovered[i], ti)
+		}
+	}
+}
+
+func TestUnmarshalBinary_Trailing(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339, "2016-12-02T08:00:00Z")
+	log.PanicIf(err)
+
+	// Adds bytes to the payload and checksums it again.
+	withTrailing := func(data []byte) []byte {
+		b := bytes.NewBuffer(append([]byte{}, data[:len(data)-4]...))
+		b.Write([]byte{0, 0})
+
+		return appendBinaryChecksum(b)
+	}
+
+	ts := TimeSlice{TimeEntry{Time: time1}, TimeEntry{Time: time1.Add(time.Second)}}
+
+	data, err := ts.MarshalBinary()
+	log.PanicIf(err)
+
+	var recoveredTs TimeSlice
+	if err := recoveredTs.UnmarshalBinary(withTrailing(data)); err != ErrBinaryTrailing {
+		t.Fatalf("Expected trailing-data error for time slice: %v", err)
+	}
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(time1, time1.Add(time.Hour), nil)
+
+	data, err = tis.MarshalBinary()
+	log.PanicIf(err)
+
+	var recoveredTis TimeIntervalSlice
+	if err := recoveredTis.UnmarshalBinary(withTrailing(data)); err != ErrBinaryTrailing {
+		t.Fatalf("Expected trailing-data error for interval slice: %v", err)
+	}
+}
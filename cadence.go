@@ -0,0 +1,218 @@
+package timeindex
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+var (
+	ErrTooFewEntries = errors.New("too few entries")
+)
+
+const (
+	// DefaultCadenceTolerance is the default `CadenceOptions.Tolerance`.
+	DefaultCadenceTolerance = 0.5
+)
+
+// CadenceOptions configures `AnalyzeCadence`.
+type CadenceOptions struct {
+	// Period is the expected time between entries. If zero, it is inferred as
+	// the median time between consecutive entries.
+	Period time.Duration
+
+	// Tolerance is the fraction of the period that a gap may differ from it
+	// by and still be considered regular. Longer gaps have missing samples and
+	// shorter gaps are bursts. Defaults to `DefaultCadenceTolerance`.
+	Tolerance float64
+}
+
+// CadenceGap is a gap between two consecutive entries in which samples are
+// missing.
+type CadenceGap struct {
+	From    time.Time
+	To      time.Time
+	Missing int
+}
+
+// CadenceBurst is a run of entries that arrived faster than the period.
+type CadenceBurst struct {
+	From  time.Time
+	To    time.Time
+	Count int
+}
+
+// CadenceReport describes how regularly the entries of a `TimeSlice` arrived.
+type CadenceReport struct {
+	// Period is the expected or inferred period.
+	Period time.Duration
+
+	// Count is the number of entries, and Expected is the number of entries
+	// that a perfect cadence would have had from the first to the last.
+	Count    int
+	Expected int
+
+	// JitterMean, JitterMax, and JitterStdDev describe how far the regular
+	// gaps differed from the period.
+	JitterMean   time.Duration
+	JitterMax    time.Duration
+	JitterStdDev time.Duration
+
+	// Missing is the total number of missing samples across `Gaps`.
+	Missing int
+	Gaps    []CadenceGap
+
+	// Duplicates are the times of entries with more than one item.
+	Duplicates []time.Time
+
+	Bursts []CadenceBurst
+}
+
+// IsRegular returns whether nothing was missing, duplicated, or bursty.
+func (cr CadenceReport) IsRegular() bool {
+	return len(cr.Gaps) == 0 && len(cr.Duplicates) == 0 && len(cr.Bursts) == 0
+}
+
+// medianGap returns the median of the positive gaps between entries.
+func (ts TimeSlice) medianGap() (median time.Duration, err error) {
+	gaps := make([]time.Duration, 0, len(ts))
+	for i := 1; i < len(ts); i++ {
+		if gap := ts[i].Time.Sub(ts[i-1].Time); gap > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+
+	if len(gaps) == 0 {
+		return 0, ErrTooFewEntries
+	}
+
+	sort.Slice(gaps, func(i, j int) bool {
+		return gaps[i] < gaps[j]
+	})
+
+	return gaps[len(gaps)/2], nil
+}
+
+// AnalyzeCadence reports the period of the entries, the jitter of the regular
+// gaps, the gaps with missing samples, entries with duplicate items, and runs
+// of entries that arrived too quickly. It returns `ErrTooFewEntries` if the
+// period must be inferred but there are fewer than two entries.
+func (ts TimeSlice) AnalyzeCadence(options CadenceOptions) (report CadenceReport, err error) {
+	period := options.Period
+	if period < 0 {
+		return report, fmt.Errorf("period must not be negative: [%s]", period)
+	} else if period == 0 {
+		period, err = ts.medianGap()
+		if err != nil {
+			return report, err
+		}
+	}
+
+	tolerance := options.Tolerance
+	if tolerance == 0 {
+		tolerance = DefaultCadenceTolerance
+	} else if tolerance < 0 || tolerance >= 1 {
+		return report, fmt.Errorf("tolerance must be between zero and one: (%f)", tolerance)
+	}
+
+	report = CadenceReport{
+		Period:     period,
+		Count:      len(ts),
+		Gaps:       make([]CadenceGap, 0),
+		Duplicates: make([]time.Time, 0),
+		Bursts:     make([]CadenceBurst, 0),
+	}
+
+	if len(ts) == 0 {
+		return report, nil
+	}
+
+	report.Expected = int(ts[len(ts)-1].Time.Sub(ts[0].Time)/period) + 1
+
+	maximumRegular := time.Duration(float64(period) * (1 + tolerance))
+	minimumRegular := time.Duration(float64(period) * (1 - tolerance))
+
+	var jitterSum, jitterSquaredSum float64
+	jitterCount := 0
+
+	var burst *CadenceBurst
+
+	for i, te := range ts {
+		if len(te.Items) > 1 {
+			report.Duplicates = append(report.Duplicates, te.Time)
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		previous := ts[i-1].Time
+		gap := te.Time.Sub(previous)
+
+		if gap < minimumRegular {
+			if burst == nil {
+				burst = &CadenceBurst{
+					From:  previous,
+					Count: 1,
+				}
+			}
+
+			burst.To = te.Time
+			burst.Count++
+
+			continue
+		}
+
+		if burst != nil {
+			report.Bursts = append(report.Bursts, *burst)
+			burst = nil
+		}
+
+		if gap > maximumRegular {
+			missing := int(math.Floor(float64(gap)/float64(period)+0.5)) - 1
+			if missing < 1 {
+				missing = 1
+			}
+
+			cg := CadenceGap{
+				From:    previous,
+				To:      te.Time,
+				Missing: missing,
+			}
+
+			report.Gaps = append(report.Gaps, cg)
+			report.Missing += missing
+
+			continue
+		}
+
+		jitter := math.Abs(float64(gap - period))
+
+		jitterSum += jitter
+		jitterSquaredSum += jitter * jitter
+		jitterCount++
+
+		if time.Duration(jitter) > report.JitterMax {
+			report.JitterMax = time.Duration(jitter)
+		}
+	}
+
+	if burst != nil {
+		report.Bursts = append(report.Bursts, *burst)
+	}
+
+	if jitterCount > 0 {
+		mean := jitterSum / float64(jitterCount)
+		variance := jitterSquaredSum/float64(jitterCount) - mean*mean
+		if variance < 0 {
+			variance = 0
+		}
+
+		report.JitterMean = time.Duration(mean)
+		report.JitterStdDev = time.Duration(math.Sqrt(variance))
+	}
+
+	return report, nil
+}
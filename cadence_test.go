@@ -0,0 +1,84 @@
+package timeindex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestTimeSlice_AnalyzeCadence_Regular(t *testing.T) {
+	ts := make(TimeSlice, 0)
+	for i := 0; i < 100; i++ {
+		// Alternate between one second early and one second late.
+		jitter := time.Second
+		if i%2 == 1 {
+			jitter = -time.Second
+		}
+
+		ts = ts.Add(getTestMinute(i).Add(jitter), i)
+	}
+
+	report, err := ts.AnalyzeCadence(CadenceOptions{})
+	log.PanicIf(err)
+
+	if report.IsRegular() == false {
+		t.Fatalf("Expected a regular cadence: %v", report)
+	} else if report.Period != time.Minute-time.Second*2 && report.Period != time.Minute+time.Second*2 {
+		t.Fatalf("Period not correct: [%s]", report.Period)
+	} else if report.Count != 100 || report.Missing != 0 {
+		t.Fatalf("Counts not correct: %v", report)
+	} else if report.JitterMax == 0 || report.JitterMean == 0 {
+		t.Fatalf("Jitter not reported: %v", report)
+	}
+}
+
+func TestTimeSlice_AnalyzeCadence_Irregular(t *testing.T) {
+	ts := make(TimeSlice, 0)
+	for _, seconds := range []int{0, 10, 20, 30, 70, 80, 81, 82, 83, 90, 100, 110} {
+		ts = ts.Add(getTestSecond(seconds), seconds)
+	}
+
+	// A duplicate sample.
+	ts = ts.Add(getTestSecond(100), -1)
+
+	report, err := ts.AnalyzeCadence(CadenceOptions{})
+	log.PanicIf(err)
+
+	if report.Period != time.Second*10 {
+		t.Fatalf("Period not correct: [%s]", report.Period)
+	} else if report.Count != 12 || report.Expected != 12 {
+		t.Fatalf("Counts not correct: (%d) (%d)", report.Count, report.Expected)
+	} else if report.Missing != 3 || len(report.Gaps) != 1 || report.Gaps[0].From.Equal(getTestSecond(30)) == false || report.Gaps[0].To.Equal(getTestSecond(70)) == false {
+		t.Fatalf("Gaps not correct: %v", report.Gaps)
+	} else if len(report.Duplicates) != 1 || report.Duplicates[0].Equal(getTestSecond(100)) == false {
+		t.Fatalf("Duplicates not correct: %v", report.Duplicates)
+	} else if len(report.Bursts) != 1 || report.Bursts[0].From.Equal(getTestSecond(80)) == false || report.Bursts[0].To.Equal(getTestSecond(83)) == false || report.Bursts[0].Count != 4 {
+		t.Fatalf("Bursts not correct: %v", report.Bursts)
+	} else if report.IsRegular() == true {
+		t.Fatalf("Expected an irregular cadence.")
+	}
+}
+
+func TestTimeSlice_AnalyzeCadence_ExpectedPeriod(t *testing.T) {
+	ts := make(TimeSlice, 0)
+	for i := 0; i < 10; i++ {
+		ts = ts.Add(getTestMinute(i*2), i)
+	}
+
+	report, err := ts.AnalyzeCadence(CadenceOptions{Period: time.Minute})
+	log.PanicIf(err)
+
+	if report.Missing != 9 || len(report.Gaps) != 9 || report.Expected != 19 {
+		t.Fatalf("Report not correct: %v", report)
+	}
+}
+
+func TestTimeSlice_AnalyzeCadence_TooFewEntries(t *testing.T) {
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(getTestEpoch(), 1)
+
+	if _, err := ts.AnalyzeCadence(CadenceOptions{}); err != ErrTooFewEntries {
+		t.Fatalf("Expected ErrTooFewEntries: %v", err)
+	}
+}
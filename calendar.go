@@ -0,0 +1,207 @@
+package timeindex
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// CalendarUnit is the size of a calendar bucket.
+type CalendarUnit int
+
+const (
+	CalendarDay CalendarUnit = iota
+	CalendarWeek
+
+	// CalendarIsoWeek is a week starting on Monday, labeled with its ISO 8601
+	// year and week number.
+	CalendarIsoWeek
+
+	CalendarMonth
+	CalendarQuarter
+	CalendarYear
+)
+
+// Calendar divides time into days, weeks, months, etc. in a given location.
+// Buckets start at local midnight, so a day may be 23 or 25 hours long across
+// a DST transition.
+type Calendar struct {
+	Unit CalendarUnit
+
+	// Location defaults to UTC.
+	Location *time.Location
+
+	// WeekStart is the first day of a `CalendarWeek` (Sunday by default).
+	// `CalendarIsoWeek` always starts on Monday.
+	WeekStart time.Weekday
+}
+
+func (c Calendar) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+
+	return c.Location
+}
+
+// startDate returns the local date that the bucket containing `t` starts on.
+func (c Calendar) startDate(t time.Time) (year int, month time.Month, day int) {
+	year, month, day = t.In(c.location()).Date()
+
+	switch c.Unit {
+	case CalendarDay:
+		return year, month, day
+	case CalendarWeek, CalendarIsoWeek:
+		weekStart := c.WeekStart
+		if c.Unit == CalendarIsoWeek {
+			weekStart = time.Monday
+		}
+
+		weekday := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Weekday()
+		offset := (int(weekday) - int(weekStart) + 7) % 7
+
+		return year, month, day - offset
+	case CalendarMonth:
+		return year, month, 1
+	case CalendarQuarter:
+		return year, (month-1)/3*3 + 1, 1
+	case CalendarYear:
+		return year, time.January, 1
+	}
+
+	panic(fmt.Errorf("calendar unit (%d) not valid", c.Unit))
+}
+
+// Start returns the start of the bucket containing `t`.
+func (c Calendar) Start(t time.Time) time.Time {
+	year, month, day := c.startDate(t)
+	return time.Date(year, month, day, 0, 0, 0, 0, c.location())
+}
+
+// Next returns the start of the bucket after the one containing `t`.
+func (c Calendar) Next(t time.Time) time.Time {
+	year, month, day := c.startDate(t)
+
+	switch c.Unit {
+	case CalendarDay:
+		day++
+	case CalendarWeek, CalendarIsoWeek:
+		day += 7
+	case CalendarMonth:
+		month++
+	case CalendarQuarter:
+		month += 3
+	case CalendarYear:
+		year++
+	}
+
+	return time.Date(year, month, day, 0, 0, 0, 0, c.location())
+}
+
+// Label returns a name for the bucket containing `t`, e.g. "2016-12-02",
+// "2016-W48", "2016-12", "2016-Q4", or "2016". A `CalendarWeek` is named by
+// the date that it starts on.
+func (c Calendar) Label(t time.Time) string {
+	start := c.Start(t)
+
+	switch c.Unit {
+	case CalendarDay, CalendarWeek:
+		return start.Format("2006-01-02")
+	case CalendarIsoWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case CalendarMonth:
+		return start.Format("2006-01")
+	case CalendarQuarter:
+		return fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())-1)/3+1)
+	case CalendarYear:
+		return start.Format("2006")
+	}
+
+	panic(fmt.Errorf("calendar unit (%d) not valid", c.Unit))
+}
+
+// CalendarBucket is like `Bucket` but with calendar buckets. The first and
+// last buckets are clipped to [from, to); pass `calendar.Start(from)` to start
+// on a bucket boundary.
+func (ts TimeSlice) CalendarBucket(from, to time.Time, calendar Calendar, aggregator Aggregator, cb func(br BucketResult) error) (err error) {
+	return ts.walkBuckets(from, to, calendar.Next, aggregator, nil, cb)
+}
+
+// CalendarBucketWithFill is like `BucketWithFill` but with calendar buckets.
+func (ts TimeSlice) CalendarBucketWithFill(from, to time.Time, calendar Calendar, aggregator Aggregator, fill interface{}, cb func(br BucketResult) error) (err error) {
+	return ts.walkBuckets(from, to, calendar.Next, aggregator, &fill, cb)
+}
+
+// CalendarDurations splits the duration of every interval across the calendar
+// buckets that it spans and calls the callback with the total for every
+// bucket in [from, to) (as a `time.Duration` value). Overlapping intervals
+// are each counted in full.
+func (tis TimeIntervalSlice) CalendarDurations(from, to time.Time, calendar Calendar, cb func(br BucketResult) error) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if from.Before(to) == false {
+		log.Panicf("bucket range is invalid: [%s] - [%s]", from, to)
+	}
+
+	boundaries := []time.Time{from}
+	for start := from; start.Before(to) == true; {
+		end := calendar.Next(start)
+		if end.After(start) == false {
+			log.Panicf("bucket does not advance: [%s]", start)
+		} else if end.After(to) == true {
+			end = to
+		}
+
+		boundaries = append(boundaries, end)
+		start = end
+	}
+
+	totals := make([]time.Duration, len(boundaries)-1)
+
+	for _, ti := range tis {
+		if ti.From.Before(to) == false {
+			break
+		} else if ti.To.After(from) == false {
+			continue
+		}
+
+		// The first bucket that ends after the interval starts.
+		i := sort.Search(len(totals), func(j int) bool {
+			return boundaries[j+1].After(ti.From)
+		})
+
+		for ; i < len(totals) && boundaries[i].Before(ti.To) == true; i++ {
+			start := boundaries[i]
+			if ti.From.After(start) == true {
+				start = ti.From
+			}
+
+			end := boundaries[i+1]
+			if ti.To.Before(end) == true {
+				end = ti.To
+			}
+
+			totals[i] += end.Sub(start)
+		}
+	}
+
+	for i, total := range totals {
+		br := BucketResult{
+			From:  boundaries[i],
+			To:    boundaries[i+1],
+			Value: total,
+		}
+
+		err := cb(br)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package timeindex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getNewYork() *time.Location {
+	location, err := time.LoadLocation("America/New_York")
+	log.PanicIf(err)
+
+	return location
+}
+
+func TestCalendar_Start(t *testing.T) {
+	location := getNewYork()
+
+	// A Friday.
+	t1 := time.Date(2016, 12, 2, 15, 30, 0, 0, location)
+
+	cases := []struct {
+		calendar Calendar
+		start    time.Time
+		next     time.Time
+		label    string
+	}{
+		{Calendar{Unit: CalendarDay, Location: location}, time.Date(2016, 12, 2, 0, 0, 0, 0, location), time.Date(2016, 12, 3, 0, 0, 0, 0, location), "2016-12-02"},
+		{Calendar{Unit: CalendarWeek, Location: location}, time.Date(2016, 11, 27, 0, 0, 0, 0, location), time.Date(2016, 12, 4, 0, 0, 0, 0, location), "2016-11-27"},
+		{Calendar{Unit: CalendarWeek, Location: location, WeekStart: time.Saturday}, time.Date(2016, 11, 26, 0, 0, 0, 0, location), time.Date(2016, 12, 3, 0, 0, 0, 0, location), "2016-11-26"},
+		{Calendar{Unit: CalendarIsoWeek, Location: location}, time.Date(2016, 11, 28, 0, 0, 0, 0, location), time.Date(2016, 12, 5, 0, 0, 0, 0, location), "2016-W48"},
+		{Calendar{Unit: CalendarMonth, Location: location}, time.Date(2016, 12, 1, 0, 0, 0, 0, location), time.Date(2017, 1, 1, 0, 0, 0, 0, location), "2016-12"},
+		{Calendar{Unit: CalendarQuarter, Location: location}, time.Date(2016, 10, 1, 0, 0, 0, 0, location), time.Date(2017, 1, 1, 0, 0, 0, 0, location), "2016-Q4"},
+		{Calendar{Unit: CalendarYear, Location: location}, time.Date(2016, 1, 1, 0, 0, 0, 0, location), time.Date(2017, 1, 1, 0, 0, 0, 0, location), "2016"},
+	}
+
+	for i, c := range cases {
+		if start := c.calendar.Start(t1); start.Equal(c.start) == false {
+			t.Fatalf("Start (%d) not correct: [%s]", i, start)
+		} else if next := c.calendar.Next(t1); next.Equal(c.next) == false {
+			t.Fatalf("Next (%d) not correct: [%s]", i, next)
+		} else if label := c.calendar.Label(t1); label != c.label {
+			t.Fatalf("Label (%d) not correct: [%s]", i, label)
+		}
+	}
+}
+
+func TestCalendar_IsoWeekAcrossYears(t *testing.T) {
+	c := Calendar{Unit: CalendarIsoWeek}
+
+	// 2017-01-01 was a Sunday and belongs to the last ISO week of 2016.
+	t1 := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if label := c.Label(t1); label != "2016-W52" {
+		t.Fatalf("Label not correct: [%s]", label)
+	} else if start := c.Start(t1); start.Equal(time.Date(2016, 12, 26, 0, 0, 0, 0, time.UTC)) == false {
+		t.Fatalf("Start not correct: [%s]", start)
+	}
+}
+
+func TestCalendar_UtcDefault(t *testing.T) {
+	c := Calendar{Unit: CalendarDay}
+
+	t1 := time.Date(2016, 12, 2, 23, 0, 0, 0, getNewYork())
+
+	if start := c.Start(t1); start.Equal(time.Date(2016, 12, 3, 0, 0, 0, 0, time.UTC)) == false {
+		t.Fatalf("Start not correct: [%s]", start)
+	}
+}
+
+// countHourlyByDay adds an entry at every hour (in absolute time) of the three
+// days around the given day and returns the count for each local day.
+func countHourlyByDay(location *time.Location, year int, month time.Month, day int) (counts []int, durations []time.Duration) {
+	from := time.Date(year, month, day-1, 0, 0, 0, 0, location)
+	to := time.Date(year, month, day+2, 0, 0, 0, 0, location)
+
+	ts := make(TimeSlice, 0)
+	for t := from; t.Before(to) == true; t = t.Add(time.Hour) {
+		ts = ts.Add(t, nil)
+	}
+
+	c := Calendar{
+		Unit:     CalendarDay,
+		Location: location,
+	}
+
+	counts = make([]int, 0)
+	durations = make([]time.Duration, 0)
+
+	cb := func(br BucketResult) error {
+		counts = append(counts, br.Value.(int))
+		durations = append(durations, br.To.Sub(br.From))
+
+		return nil
+	}
+
+	err := ts.CalendarBucket(from, to, c, NewCountAggregator(), cb)
+	log.PanicIf(err)
+
+	return counts, durations
+}
+
+func TestTimeSlice_CalendarBucket_SpringForward(t *testing.T) {
+	counts, durations := countHourlyByDay(getNewYork(), 2016, 3, 13)
+
+	if len(counts) != 3 || counts[0] != 24 || counts[1] != 23 || counts[2] != 24 {
+		t.Fatalf("Counts not correct: %v", counts)
+	} else if durations[1] != time.Hour*23 {
+		t.Fatalf("Durations not correct: %v", durations)
+	}
+}
+
+func TestTimeSlice_CalendarBucket_FallBack(t *testing.T) {
+	counts, durations := countHourlyByDay(getNewYork(), 2016, 11, 6)
+
+	if len(counts) != 3 || counts[0] != 24 || counts[1] != 25 || counts[2] != 24 {
+		t.Fatalf("Counts not correct: %v", counts)
+	} else if durations[1] != time.Hour*25 {
+		t.Fatalf("Durations not correct: %v", durations)
+	}
+}
+
+func TestTimeSlice_CalendarBucketWithFill_Months(t *testing.T) {
+	from := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2016, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(time.Date(2016, 1, 31, 23, 59, 59, 0, time.UTC), 1)
+	ts = ts.Add(time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC), 2)
+	ts = ts.Add(time.Date(2016, 3, 31, 0, 0, 0, 0, time.UTC), 3)
+
+	c := Calendar{Unit: CalendarMonth}
+
+	labels := make([]string, 0)
+	values := make([]interface{}, 0)
+
+	cb := func(br BucketResult) error {
+		labels = append(labels, c.Label(br.From))
+		values = append(values, br.Value)
+
+		return nil
+	}
+
+	err := ts.CalendarBucketWithFill(from, to, c, NewSumAggregator(nil), 0.0, cb)
+	log.PanicIf(err)
+
+	if len(labels) != 3 || labels[0] != "2016-01" || labels[1] != "2016-02" || labels[2] != "2016-03" {
+		t.Fatalf("Labels not correct: %v", labels)
+	} else if values[0] != 1.0 || values[1] != 0.0 || values[2] != 5.0 {
+		t.Fatalf("Values not correct: %v", values)
+	}
+}
+
+func TestTimeIntervalSlice_CalendarDurations(t *testing.T) {
+	location := getNewYork()
+
+	c := Calendar{
+		Unit:     CalendarDay,
+		Location: location,
+	}
+
+	from := time.Date(2016, 11, 5, 0, 0, 0, 0, location)
+	to := time.Date(2016, 11, 8, 0, 0, 0, 0, location)
+
+	tis := make(TimeIntervalSlice, 0)
+
+	// Spans from the evening before the DST change to the morning after.
+	tis = tis.Add(time.Date(2016, 11, 5, 22, 0, 0, 0, location), time.Date(2016, 11, 7, 2, 0, 0, 0, location), "long")
+
+	// Overlaps the first.
+	tis = tis.Add(time.Date(2016, 11, 6, 12, 0, 0, 0, location), time.Date(2016, 11, 6, 13, 0, 0, 0, location), "short")
+
+	// Starts before the range.
+	tis = tis.Add(time.Date(2016, 11, 4, 23, 0, 0, 0, location), time.Date(2016, 11, 5, 1, 0, 0, 0, location), "early")
+
+	durations := make([]time.Duration, 0)
+
+	cb := func(br BucketResult) error {
+		durations = append(durations, br.Value.(time.Duration))
+		return nil
+	}
+
+	err := tis.CalendarDurations(from, to, c, cb)
+	log.PanicIf(err)
+
+	expected := []time.Duration{
+		time.Hour * 3,
+		time.Hour * 26,
+		time.Hour * 2,
+	}
+
+	if len(durations) != len(expected) {
+		t.Fatalf("Bucket count not correct: %v", durations)
+	}
+
+	for i, d := range expected {
+		if durations[i] != d {
+			t.Fatalf("Durations not correct: %v", durations)
+		}
+	}
+}
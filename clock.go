@@ -0,0 +1,169 @@
+package timeindex
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the source of the current time for anything that depends on it, so
+// that it can be replaced in tests.
+type Clock interface {
+	Now() time.Time
+
+	// NewTimer behaves like `time.NewTimer`.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a `time.Timer` obtained from a `Clock`. Stop it once it is no
+// longer needed.
+type Timer interface {
+	// C returns the channel that receives the time when the timer fires.
+	C() <-chan time.Time
+
+	// Stop behaves like `time.Timer.Stop`.
+	Stop() bool
+}
+
+// RealClock is the system clock.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (RealClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (rt realTimer) C() <-chan time.Time {
+	return rt.Timer.C
+}
+
+var (
+	// DefaultClock is used by anything that needs the current time and isn't
+	// given a `Clock`. Tests may replace it.
+	DefaultClock Clock = RealClock{}
+)
+
+type fakeClockTimer struct {
+	fc       *FakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (fct *fakeClockTimer) C() <-chan time.Time {
+	return fct.c
+}
+
+// Stop removes the timer if it hasn't fired yet.
+func (fct *fakeClockTimer) Stop() bool {
+	fc := fct.fc
+
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	for i, waiter := range fc.waiters {
+		if waiter == fct {
+			fc.waiters = append(fc.waiters[:i], fc.waiters[i+1:]...)
+			fc.changed.Broadcast()
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// FakeClock is a `Clock` whose time only moves when `Advance` is called.
+type FakeClock struct {
+	lock    sync.Mutex
+	changed *sync.Cond
+
+	now time.Time
+
+	// waiters are the timers that haven't fired or been stopped.
+	waiters []*fakeClockTimer
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	fc := &FakeClock{
+		now:     now,
+		waiters: make([]*fakeClockTimer, 0),
+	}
+
+	fc.changed = sync.NewCond(&fc.lock)
+
+	return fc
+}
+
+func (fc *FakeClock) Now() time.Time {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	return fc.now
+}
+
+// NewTimer returns a timer that fires once the clock has been advanced by at
+// least `d`.
+func (fc *FakeClock) NewTimer(d time.Duration) Timer {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	fct := &fakeClockTimer{
+		fc:       fc,
+		deadline: fc.now.Add(d),
+		c:        make(chan time.Time, 1),
+	}
+
+	if d <= 0 {
+		fct.c <- fc.now
+		return fct
+	}
+
+	fc.waiters = append(fc.waiters, fct)
+	fc.changed.Broadcast()
+
+	return fct
+}
+
+// Advance moves the clock forward and fires the timers that are due, in
+// order of their deadlines.
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	fc.now = fc.now.Add(d)
+
+	sort.SliceStable(fc.waiters, func(i, j int) bool {
+		return fc.waiters[i].deadline.Before(fc.waiters[j].deadline)
+	})
+
+	remaining := make([]*fakeClockTimer, 0, len(fc.waiters))
+	for _, waiter := range fc.waiters {
+		if waiter.deadline.After(fc.now) == true {
+			remaining = append(remaining, waiter)
+			continue
+		}
+
+		waiter.c <- fc.now
+	}
+
+	fc.waiters = remaining
+	fc.changed.Broadcast()
+}
+
+// WaitForWaiters blocks until at least `n` timers are waiting. This lets a
+// test know that a goroutine has gone to sleep before advancing the clock.
+func (fc *FakeClock) WaitForWaiters(n int) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	for len(fc.waiters) < n {
+		fc.changed.Wait()
+	}
+}
@@ -0,0 +1,103 @@
+package timeindex
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := getTestEpoch()
+
+	fc := NewFakeClock(start)
+
+	if fc.Now().Equal(start) == false {
+		t.Fatalf("Now not correct: [%s]", fc.Now())
+	}
+
+	c1 := fc.NewTimer(time.Second * 10).C()
+	c2 := fc.NewTimer(time.Second * 5).C()
+
+	fc.Advance(time.Second * 5)
+
+	select {
+	case fired := <-c2:
+		if fired.Equal(start.Add(time.Second*5)) == false {
+			t.Fatalf("Fired time not correct: [%s]", fired)
+		}
+	default:
+		t.Fatalf("Expected the first waiter to fire.")
+	}
+
+	select {
+	case <-c1:
+		t.Fatalf("Expected the second waiter not to fire yet.")
+	default:
+	}
+
+	fc.Advance(time.Second * 5)
+
+	select {
+	case <-c1:
+	default:
+		t.Fatalf("Expected the second waiter to fire.")
+	}
+
+	select {
+	case <-fc.NewTimer(0).C():
+	default:
+		t.Fatalf("Expected an immediate waiter to fire.")
+	}
+}
+
+func TestFakeClock_WaitForWaiters(t *testing.T) {
+	fc := NewFakeClock(getTestEpoch())
+
+	fired := make(chan struct{})
+
+	go func() {
+		<-fc.NewTimer(time.Minute).C()
+		close(fired)
+	}()
+
+	fc.WaitForWaiters(1)
+	fc.Advance(time.Minute)
+
+	<-fired
+}
+
+func TestRealClock(t *testing.T) {
+	c := RealClock{}
+
+	if time.Since(c.Now()) > time.Minute {
+		t.Fatalf("Now not correct: [%s]", c.Now())
+	}
+
+	<-c.NewTimer(time.Millisecond).C()
+
+	if c.NewTimer(time.Hour).Stop() == false {
+		t.Fatalf("Expected a pending timer to stop.")
+	}
+}
+
+func TestFakeClock_Stop(t *testing.T) {
+	fc := NewFakeClock(getTestEpoch())
+
+	timer := fc.NewTimer(time.Minute)
+	if timer.Stop() == false {
+		t.Fatalf("Expected a pending timer to stop.")
+	} else if len(fc.waiters) != 0 {
+		t.Fatalf("Stopped timer still waiting.")
+	}
+
+	fc.Advance(time.Minute)
+
+	select {
+	case <-timer.C():
+		t.Fatalf("Expected a stopped timer not to fire.")
+	default:
+	}
+
+	if timer.Stop() == true {
+		t.Fatalf("Expected a second stop to do nothing.")
+	}
+}
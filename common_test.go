@@ -0,0 +1,25 @@
+package timeindex
+
+import (
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// getTestEpoch returns the time that the test fixtures are relative to.
+func getTestEpoch() time.Time {
+	epoch, err := time.Parse(time.RFC3339, "2016-12-02T08:00:00Z")
+	log.PanicIf(err)
+
+	return epoch
+}
+
+// getTestSecond returns the time `seconds` after the test epoch.
+func getTestSecond(seconds int) time.Time {
+	return getTestEpoch().Add(time.Duration(seconds) * time.Second)
+}
+
+// getTestMinute returns the time `minutes` after the test epoch.
+func getTestMinute(minutes int) time.Time {
+	return getTestEpoch().Add(time.Duration(minutes) * time.Minute)
+}
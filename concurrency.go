@@ -0,0 +1,97 @@
+package timeindex
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// intervalEdge is the start (+1) or end (-1) of an interval.
+type intervalEdge struct {
+	t     time.Time
+	delta int
+}
+
+// sortedEdges returns the starts and ends of the intervals in time order.
+// Since intervals are half-open, an end sorts before a start at the same
+// time.
+func (tis TimeIntervalSlice) sortedEdges() []intervalEdge {
+	edges := make([]intervalEdge, 0, len(tis)*2)
+	for _, ti := range tis {
+		edges = append(edges, intervalEdge{t: ti.From, delta: 1}, intervalEdge{t: ti.To, delta: -1})
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].t.Equal(edges[j].t) == true {
+			return edges[i].delta < edges[j].delta
+		}
+
+		return edges[i].t.Before(edges[j].t)
+	})
+
+	return edges
+}
+
+// ConcurrencyProfile returns the number of intervals active at every time that
+// it changes, as a step function: each entry has the count (int) from its time
+// until the next entry as its only item. Intervals are treated as half-open,
+// so an interval ending when another starts does not overlap it. The last
+// entry, if any, has a count of zero.
+func (tis TimeIntervalSlice) ConcurrencyProfile() (profile TimeSlice) {
+	profile = make(TimeSlice, 0)
+
+	edges := tis.sortedEdges()
+
+	count := 0
+	for i := 0; i < len(edges); {
+		t := edges[i].t
+
+		previous := count
+		for ; i < len(edges) && edges[i].t.Equal(t) == true; i++ {
+			count += edges[i].delta
+		}
+
+		if count == previous {
+			continue
+		}
+
+		te := TimeEntry{
+			Time:  t,
+			Items: []interface{}{count},
+		}
+
+		profile = append(profile, te)
+	}
+
+	return profile
+}
+
+// PeakConcurrency returns the largest number of intervals active at once
+// within [from, to) and the earliest time that it occurred (clipped to
+// `from`). If no interval overlaps the range, the peak is zero at `from`.
+func (tis TimeIntervalSlice) PeakConcurrency(from, to time.Time) (peak int, at time.Time, err error) {
+	if from.Before(to) == false {
+		return 0, at, fmt.Errorf("range is invalid: [%s] - [%s]", from, to)
+	}
+
+	profile := tis.ConcurrencyProfile()
+
+	// The first change after `from`.
+	i := sort.Search(len(profile), func(j int) bool {
+		return profile[j].Time.After(from)
+	})
+
+	at = from
+	if i > 0 {
+		peak = profile[i-1].Items[0].(int)
+	}
+
+	for ; i < len(profile) && profile[i].Time.Before(to) == true; i++ {
+		if count := profile[i].Items[0].(int); count > peak {
+			peak = count
+			at = profile[i].Time
+		}
+	}
+
+	return peak, at, nil
+}
@@ -0,0 +1,88 @@
+package timeindex
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getConcurrencyTestSlice() (tis TimeIntervalSlice) {
+	tis = make(TimeIntervalSlice, 0)
+	tis = tis.Add(getTestMinute(0), getTestMinute(10), "a")
+	tis = tis.Add(getTestMinute(5), getTestMinute(15), "b")
+
+	// Starts exactly when "a" ends.
+	tis = tis.Add(getTestMinute(10), getTestMinute(20), "c")
+
+	tis = tis.Add(getTestMinute(12), getTestMinute(14), "d")
+	tis = tis.Add(getTestMinute(30), getTestMinute(40), "e")
+
+	return tis
+}
+
+func TestTimeIntervalSlice_ConcurrencyProfile(t *testing.T) {
+	tis := getConcurrencyTestSlice()
+
+	profile := tis.ConcurrencyProfile()
+
+	expected := []struct {
+		minutes int
+		count   int
+	}{
+		{0, 1},
+		{5, 2},
+		{12, 3},
+		{14, 2},
+		{15, 1},
+		{20, 0},
+		{30, 1},
+		{40, 0},
+	}
+
+	if len(profile) != len(expected) {
+		t.Fatalf("Profile not correct: %v", profile)
+	}
+
+	for i, e := range expected {
+		if profile[i].Time.Equal(getTestMinute(e.minutes)) == false || profile[i].Items[0] != e.count {
+			t.Fatalf("Change (%d) not correct: %v", i, profile[i])
+		}
+	}
+}
+
+func TestTimeIntervalSlice_ConcurrencyProfile_Empty(t *testing.T) {
+	profile := TimeIntervalSlice{}.ConcurrencyProfile()
+	if len(profile) != 0 {
+		t.Fatalf("Profile not empty: %v", profile)
+	}
+}
+
+func TestTimeIntervalSlice_PeakConcurrency(t *testing.T) {
+	tis := getConcurrencyTestSlice()
+
+	cases := []struct {
+		from, to int
+		peak     int
+		at       int
+	}{
+		{0, 60, 3, 12},
+		{13, 60, 3, 13},
+		{14, 60, 2, 14},
+		{0, 12, 2, 5},
+		{20, 30, 0, 20},
+		{25, 35, 1, 30},
+	}
+
+	for i, c := range cases {
+		peak, peakAt, err := tis.PeakConcurrency(getTestMinute(c.from), getTestMinute(c.to))
+		log.PanicIf(err)
+
+		if peak != c.peak || peakAt.Equal(getTestMinute(c.at)) == false {
+			t.Fatalf("Case (%d) not correct: (%d) [%s]", i, peak, peakAt)
+		}
+	}
+
+	if _, _, err := tis.PeakConcurrency(getTestMinute(10), getTestMinute(10)); err == nil {
+		t.Fatalf("Expected error for empty range.")
+	}
+}
@@ -0,0 +1,174 @@
+package timeindex
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// coveredDuration returns how much of [from, to) is covered by at least one
+// of the intervals, which must be sorted by `From`.
+func coveredDuration(tis []TimeInterval, from, to time.Time) (covered time.Duration) {
+	if from.Before(to) == false {
+		return 0
+	}
+
+	// The run of overlapping intervals being merged.
+	var runStart, runEnd time.Time
+	isRunning := false
+
+	for _, ti := range tis {
+		start, end := ti.From, ti.To
+		if start.Before(from) == true {
+			start = from
+		}
+
+		if end.After(to) == true {
+			end = to
+		}
+
+		if start.Before(end) == false {
+			if ti.From.Before(to) == false {
+				break
+			}
+
+			continue
+		}
+
+		if isRunning == true && start.After(runEnd) == false {
+			if end.After(runEnd) == true {
+				runEnd = end
+			}
+
+			continue
+		}
+
+		if isRunning == true {
+			covered += runEnd.Sub(runStart)
+		}
+
+		runStart, runEnd = start, end
+		isRunning = true
+	}
+
+	if isRunning == true {
+		covered += runEnd.Sub(runStart)
+	}
+
+	return covered
+}
+
+// Coverage returns how much of [from, to) is covered by at least one interval.
+// Overlapping intervals are not counted twice.
+func (tis TimeIntervalSlice) Coverage(from, to time.Time) time.Duration {
+	return coveredDuration(tis, from, to)
+}
+
+// Utilization returns the fraction (0 to 1) of [from, to) that is covered by
+// at least one interval.
+func (tis TimeIntervalSlice) Utilization(from, to time.Time) float64 {
+	if from.Before(to) == false {
+		return 0
+	}
+
+	return float64(tis.Coverage(from, to)) / float64(to.Sub(from))
+}
+
+// BusyTimeByItem returns, for every item, how much of [from, to) is covered by
+// the intervals that carry it. Overlapping intervals with the same item are
+// not counted twice. `key` returns the map key of an item; if nil, the item
+// itself is the key. Keys must be comparable, so items like the maps decoded
+// from JSON or the []string rows read from CSV need a `key` (e.g. one that
+// returns a field of the map).
+func (tis TimeIntervalSlice) BusyTimeByItem(from, to time.Time, key func(item interface{}) interface{}) (busy map[interface{}]time.Duration, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	byItem := make(map[interface{}][]TimeInterval)
+	for _, ti := range tis {
+		for _, item := range ti.Items {
+			k := item
+			if key != nil {
+				k = key(item)
+			}
+
+			if k != nil && reflect.TypeOf(k).Comparable() == false {
+				log.Panicf("item key is not comparable: [%T]", k)
+			}
+
+			byItem[k] = append(byItem[k], ti)
+		}
+	}
+
+	busy = make(map[interface{}]time.Duration, len(byItem))
+	for k, intervals := range byItem {
+		busy[k] = coveredDuration(intervals, from, to)
+	}
+
+	return busy, nil
+}
+
+// DurationStatistics describes the durations (`To` minus `From`) of a set of
+// intervals.
+type DurationStatistics struct {
+	Count int
+	Min   time.Duration
+	Max   time.Duration
+	Mean  time.Duration
+
+	// Total is the sum of the durations (overlaps are counted for every
+	// interval; see `Coverage` for the time covered).
+	Total time.Duration
+
+	sorted []time.Duration
+}
+
+// DurationStatistics returns the statistics of the interval durations.
+func (tis TimeIntervalSlice) DurationStatistics() (ds DurationStatistics) {
+	ds.sorted = make([]time.Duration, len(tis))
+	for i, ti := range tis {
+		ds.sorted[i] = ti.To.Sub(ti.From)
+	}
+
+	sort.Slice(ds.sorted, func(i, j int) bool {
+		return ds.sorted[i] < ds.sorted[j]
+	})
+
+	ds.Count = len(ds.sorted)
+	if ds.Count == 0 {
+		return ds
+	}
+
+	for _, d := range ds.sorted {
+		ds.Total += d
+	}
+
+	ds.Min = ds.sorted[0]
+	ds.Max = ds.sorted[ds.Count-1]
+	ds.Mean = ds.Total / time.Duration(ds.Count)
+
+	return ds
+}
+
+// Percentile returns the duration at the given percentile (0 to 100) using the
+// nearest-rank method, or zero if there are no intervals.
+func (ds DurationStatistics) Percentile(p float64) time.Duration {
+	if ds.Count == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(ds.Count)))
+	if rank < 1 {
+		rank = 1
+	} else if rank > ds.Count {
+		rank = ds.Count
+	}
+
+	return ds.sorted[rank-1]
+}
@@ -0,0 +1,130 @@
+package timeindex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getCoverageTestSlice() (tis TimeIntervalSlice) {
+	tis = make(TimeIntervalSlice, 0)
+	tis = tis.Add(getTestMinute(0), getTestMinute(10), "a")
+	tis = tis.Add(getTestMinute(5), getTestMinute(15), "b")
+	tis = tis.Add(getTestMinute(6), getTestMinute(8), "a")
+	tis = tis.Add(getTestMinute(20), getTestMinute(30), "a")
+	tis = tis.Add(getTestMinute(25), getTestMinute(26), "c")
+	tis = tis.Add(getTestMinute(50), getTestMinute(90), "b")
+
+	return tis
+}
+
+func TestTimeIntervalSlice_Coverage(t *testing.T) {
+	tis := getCoverageTestSlice()
+
+	cases := []struct {
+		from, to int
+		covered  int
+	}{
+		{0, 100, 65},
+		{-10, 100, 65},
+		{5, 25, 15},
+		{15, 20, 0},
+		{60, 70, 10},
+		{100, 200, 0},
+	}
+
+	for i, c := range cases {
+		if covered := tis.Coverage(getTestMinute(c.from), getTestMinute(c.to)); covered != time.Duration(c.covered)*time.Minute {
+			t.Fatalf("Case (%d) not correct: [%s]", i, covered)
+		}
+	}
+
+	if covered := tis.Coverage(getTestMinute(10), getTestMinute(0)); covered != 0 {
+		t.Fatalf("Expected no coverage for an inverted range: [%s]", covered)
+	}
+}
+
+func TestTimeIntervalSlice_Utilization(t *testing.T) {
+	tis := getCoverageTestSlice()
+
+	if u := tis.Utilization(getTestMinute(0), getTestMinute(100)); u != 0.65 {
+		t.Fatalf("Utilization not correct: (%f)", u)
+	} else if u := tis.Utilization(getTestMinute(60), getTestMinute(70)); u != 1 {
+		t.Fatalf("Full utilization not correct: (%f)", u)
+	} else if u := tis.Utilization(getTestMinute(0), getTestMinute(0)); u != 0 {
+		t.Fatalf("Empty-range utilization not correct: (%f)", u)
+	}
+}
+
+func TestTimeIntervalSlice_BusyTimeByItem(t *testing.T) {
+	tis := getCoverageTestSlice()
+
+	busy, err := tis.BusyTimeByItem(getTestMinute(0), getTestMinute(60), nil)
+	log.PanicIf(err)
+
+	if len(busy) != 3 {
+		t.Fatalf("Item count not correct: %v", busy)
+	} else if busy["a"] != time.Minute*20 {
+		t.Fatalf("Busy time of (a) not correct: [%s]", busy["a"])
+	} else if busy["b"] != time.Minute*20 {
+		t.Fatalf("Busy time of (b) not correct: [%s]", busy["b"])
+	} else if busy["c"] != time.Minute {
+		t.Fatalf("Busy time of (c) not correct: [%s]", busy["c"])
+	}
+}
+
+func TestTimeIntervalSlice_BusyTimeByItem_MapItems(t *testing.T) {
+	// Items as decoded from JSON.
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(getTestMinute(0), getTestMinute(10), map[string]interface{}{"host": "a", "id": 1.0})
+	tis = tis.Add(getTestMinute(5), getTestMinute(20), map[string]interface{}{"host": "a", "id": 2.0})
+	tis = tis.Add(getTestMinute(0), getTestMinute(30), map[string]interface{}{"host": "b", "id": 3.0})
+
+	if _, err := tis.BusyTimeByItem(getTestMinute(0), getTestMinute(60), nil); err == nil {
+		t.Fatalf("Expected error for map items without a key.")
+	}
+
+	key := func(item interface{}) interface{} {
+		return item.(map[string]interface{})["host"]
+	}
+
+	busy, err := tis.BusyTimeByItem(getTestMinute(0), getTestMinute(60), key)
+	log.PanicIf(err)
+
+	if len(busy) != 2 || busy["a"] != time.Minute*20 || busy["b"] != time.Minute*30 {
+		t.Fatalf("Busy time not correct: %v", busy)
+	}
+
+	// A key that is only comparable by type still fails cleanly.
+	badKey := func(item interface{}) interface{} {
+		return struct{ Item interface{} }{item}
+	}
+
+	if _, err := tis.BusyTimeByItem(getTestMinute(0), getTestMinute(60), badKey); err == nil {
+		t.Fatalf("Expected error for keys that hold maps.")
+	}
+}
+
+func TestTimeIntervalSlice_DurationStatistics(t *testing.T) {
+	tis := getCoverageTestSlice()
+
+	ds := tis.DurationStatistics()
+
+	if ds.Count != 6 || ds.Min != time.Minute || ds.Max != time.Minute*40 || ds.Total != time.Minute*73 {
+		t.Fatalf("Statistics not correct: %v", ds)
+	} else if ds.Mean != time.Minute*73/6 {
+		t.Fatalf("Mean not correct: [%s]", ds.Mean)
+	} else if p := ds.Percentile(50); p != time.Minute*10 {
+		t.Fatalf("Median not correct: [%s]", p)
+	} else if p := ds.Percentile(0); p != time.Minute {
+		t.Fatalf("0th percentile not correct: [%s]", p)
+	} else if p := ds.Percentile(90); p != time.Minute*40 {
+		t.Fatalf("90th percentile not correct: [%s]", p)
+	}
+
+	empty := TimeIntervalSlice{}.DurationStatistics()
+	if empty.Count != 0 || empty.Percentile(50) != 0 {
+		t.Fatalf("Empty statistics not correct: %v", empty)
+	}
+}
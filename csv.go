@@ -0,0 +1,432 @@
+package timeindex
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// CsvOptions describes how times and items are laid out in CSV rows. Column
+// indices are zero-based.
+type CsvOptions struct {
+	// Comma is the field delimiter. Defaults to ','.
+	Comma rune
+
+	// HasHeader indicates that the first row is a header. When reading, it is
+	// skipped. When writing, `Header` is written, or, if it is empty, a header
+	// named after the columns of the first row ("time" or "from" and "to",
+	// then "item" or "item1", "item2", etc.).
+	HasHeader bool
+	Header    []string
+
+	// TimeLayout is the `time.Parse` layout of the times. Defaults to
+	// `time.RFC3339Nano`.
+	TimeLayout string
+
+	// Location is used for times whose layout has no zone. Defaults to UTC.
+	Location *time.Location
+
+	// TimeColumn is the column of the time when reading a `TimeSlice`.
+	TimeColumn int
+
+	// FromColumn and ToColumn are the columns of the start and stop times
+	// when reading a `TimeIntervalSlice`.
+	FromColumn int
+	ToColumn   int
+
+	// ItemColumns are the columns that make up the item. If empty, rows have
+	// no item.
+	ItemColumns []int
+
+	// ItemParser converts the item columns to an item. By default, the item
+	// is the []string of the item columns.
+	ItemParser func(fields []string) (item interface{}, err error)
+
+	// ItemFormatter converts an item to the fields written after the times.
+	// By default, a []string is written as-is and anything else is written as
+	// one field using `fmt.Sprint`.
+	ItemFormatter func(item interface{}) (fields []string, err error)
+}
+
+func (options CsvOptions) timeLayout() string {
+	if options.TimeLayout == "" {
+		return time.RFC3339Nano
+	}
+
+	return options.TimeLayout
+}
+
+func (options CsvOptions) location() *time.Location {
+	if options.Location == nil {
+		return time.UTC
+	}
+
+	return options.Location
+}
+
+func (options CsvOptions) parseTime(record []string, column int) (t time.Time, err error) {
+	if column >= len(record) {
+		return t, fmt.Errorf("time column (%d) not in row with (%d) fields", column, len(record))
+	}
+
+	return time.ParseInLocation(options.timeLayout(), record[column], options.location())
+}
+
+// parseItem returns the item of the row. A row that has none of the item
+// columns (as written for an entry without items) has no item.
+func (options CsvOptions) parseItem(record []string) (item interface{}, err error) {
+	if len(options.ItemColumns) == 0 {
+		return nil, nil
+	}
+
+	fields := make([]string, len(options.ItemColumns))
+	present := 0
+	for i, column := range options.ItemColumns {
+		if column < len(record) {
+			fields[i] = record[column]
+			present++
+		}
+	}
+
+	if present == 0 {
+		return nil, nil
+	} else if present != len(fields) {
+		return nil, fmt.Errorf("item columns %v not all in row with (%d) fields", options.ItemColumns, len(record))
+	}
+
+	if options.ItemParser != nil {
+		return options.ItemParser(fields)
+	}
+
+	return fields, nil
+}
+
+func (options CsvOptions) formatItem(item interface{}) (fields []string, err error) {
+	if options.ItemFormatter != nil {
+		return options.ItemFormatter(item)
+	}
+
+	if fields, ok := item.([]string); ok == true {
+		return fields, nil
+	}
+
+	return []string{fmt.Sprint(item)}, nil
+}
+
+func (options CsvOptions) newReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	if options.Comma != 0 {
+		cr.Comma = options.Comma
+	}
+
+	return cr
+}
+
+// readCsvRecords calls the callback with each row, one at a time, skipping the
+// header.
+func readCsvRecords(r io.Reader, options CsvOptions, cb func(record []string) error) (err error) {
+	cr := options.newReader(r)
+
+	isFirst := true
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if isFirst == true && options.HasHeader == true {
+			isFirst = false
+			continue
+		}
+
+		isFirst = false
+
+		if err := cb(record); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadTimeSliceCsv calls the callback with the time and item of each row
+// without holding the rows in memory.
+func ReadTimeSliceCsv(r io.Reader, options CsvOptions, cb func(t time.Time, item interface{}) error) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	recordCb := func(record []string) error {
+		t, err := options.parseTime(record, options.TimeColumn)
+		if err != nil {
+			return err
+		}
+
+		item, err := options.parseItem(record)
+		if err != nil {
+			return err
+		}
+
+		return cb(t, item)
+	}
+
+	err = readCsvRecords(r, options, recordCb)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// LoadTimeSliceCsv loads a `TimeSlice` from CSV. The rows do not have to be
+// sorted.
+func LoadTimeSliceCsv(r io.Reader, options CsvOptions) (ts TimeSlice, err error) {
+	ts = make(TimeSlice, 0)
+
+	cb := func(t time.Time, item interface{}) error {
+		te := TimeEntry{
+			Time:  t,
+			Items: []interface{}{},
+		}
+
+		if item != nil {
+			te.Items = []interface{}{item}
+		}
+
+		ts = append(ts, te)
+
+		return nil
+	}
+
+	if err := ReadTimeSliceCsv(r, options, cb); err != nil {
+		return nil, err
+	}
+
+	return sortAndCombineTimeSlice(ts), nil
+}
+
+// ReadTimeIntervalSliceCsv calls the callback with the interval and item of
+// each row without holding the rows in memory.
+func ReadTimeIntervalSliceCsv(r io.Reader, options CsvOptions, cb func(from, to time.Time, item interface{}) error) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	recordCb := func(record []string) error {
+		from, err := options.parseTime(record, options.FromColumn)
+		if err != nil {
+			return err
+		}
+
+		to, err := options.parseTime(record, options.ToColumn)
+		if err != nil {
+			return err
+		}
+
+		if from.Before(to) == false {
+			return fmt.Errorf("interval is invalid: %v", record)
+		}
+
+		item, err := options.parseItem(record)
+		if err != nil {
+			return err
+		}
+
+		return cb(from, to, item)
+	}
+
+	err = readCsvRecords(r, options, recordCb)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// LoadTimeIntervalSliceCsv loads a `TimeIntervalSlice` from CSV. The rows do
+// not have to be sorted.
+func LoadTimeIntervalSliceCsv(r io.Reader, options CsvOptions) (tis TimeIntervalSlice, err error) {
+	tis = make(TimeIntervalSlice, 0)
+
+	cb := func(from, to time.Time, item interface{}) error {
+		ti := TimeInterval{
+			From:  from,
+			To:    to,
+			Items: []interface{}{},
+		}
+
+		if item != nil {
+			ti.Items = []interface{}{item}
+		}
+
+		tis = append(tis, ti)
+
+		return nil
+	}
+
+	if err := ReadTimeIntervalSliceCsv(r, options, cb); err != nil {
+		return nil, err
+	}
+
+	return sortAndCombineTimeIntervalSlice(tis), nil
+}
+
+// CsvWriter writes times or intervals as CSV rows, one row per item. Rows are
+// the time (or the start and stop times) followed by the item fields. Rows are
+// buffered by the underlying `csv.Writer`; call `Flush` when done.
+type CsvWriter struct {
+	cw            *csv.Writer
+	options       CsvOptions
+	headerWritten bool
+}
+
+func NewCsvWriter(w io.Writer, options CsvOptions) *CsvWriter {
+	cw := csv.NewWriter(w)
+	if options.Comma != 0 {
+		cw.Comma = options.Comma
+	}
+
+	return &CsvWriter{
+		cw:      cw,
+		options: options,
+	}
+}
+
+// writeHeader writes the header if there is one and it hasn't been written
+// yet. Without a `Header`, the columns are named for the given number of
+// times and item fields.
+func (cw *CsvWriter) writeHeader(times int, itemFields int) (err error) {
+	if cw.headerWritten == true {
+		return nil
+	}
+
+	cw.headerWritten = true
+
+	if cw.options.HasHeader == false {
+		return nil
+	}
+
+	header := cw.options.Header
+	if len(header) == 0 {
+		if times == 1 {
+			header = []string{"time"}
+		} else {
+			header = []string{"from", "to"}
+		}
+
+		if itemFields == 1 {
+			header = append(header, "item")
+		} else {
+			for i := 1; i <= itemFields; i++ {
+				header = append(header, fmt.Sprintf("item%d", i))
+			}
+		}
+	}
+
+	return cw.cw.Write(header)
+}
+
+func (cw *CsvWriter) writeRow(times []time.Time, item interface{}, hasItem bool) (err error) {
+	record := make([]string, len(times))
+	for i, t := range times {
+		record[i] = t.In(cw.options.location()).Format(cw.options.timeLayout())
+	}
+
+	if hasItem == true {
+		fields, err := cw.options.formatItem(item)
+		if err != nil {
+			return err
+		}
+
+		record = append(record, fields...)
+	}
+
+	if err := cw.writeHeader(len(times), len(record)-len(times)); err != nil {
+		return err
+	}
+
+	return cw.cw.Write(record)
+}
+
+// WriteEntry writes one row per item of the entry (or one row with only the
+// time if there are no items).
+func (cw *CsvWriter) WriteEntry(te TimeEntry) (err error) {
+	times := []time.Time{te.Time}
+
+	if len(te.Items) == 0 {
+		return cw.writeRow(times, nil, false)
+	}
+
+	for _, item := range te.Items {
+		if err := cw.writeRow(times, item, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteInterval writes one row per item of the interval (or one row with only
+// the times if there are no items).
+func (cw *CsvWriter) WriteInterval(ti TimeInterval) (err error) {
+	times := []time.Time{ti.From, ti.To}
+
+	if len(ti.Items) == 0 {
+		return cw.writeRow(times, nil, false)
+	}
+
+	for _, item := range ti.Items {
+		if err := cw.writeRow(times, item, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush writes any buffered rows.
+func (cw *CsvWriter) Flush() (err error) {
+	cw.cw.Flush()
+	return cw.cw.Error()
+}
+
+// WriteTimeSliceCsv writes the slice as CSV.
+func WriteTimeSliceCsv(w io.Writer, ts TimeSlice, options CsvOptions) (err error) {
+	cw := NewCsvWriter(w, options)
+
+	for _, te := range ts {
+		if err := cw.WriteEntry(te); err != nil {
+			return err
+		}
+	}
+
+	// Write the header even if there were no rows.
+	if err := cw.writeHeader(1, 0); err != nil {
+		return err
+	}
+
+	return cw.Flush()
+}
+
+// WriteTimeIntervalSliceCsv writes the slice as CSV.
+func WriteTimeIntervalSliceCsv(w io.Writer, tis TimeIntervalSlice, options CsvOptions) (err error) {
+	cw := NewCsvWriter(w, options)
+
+	for _, ti := range tis {
+		if err := cw.WriteInterval(ti); err != nil {
+			return err
+		}
+	}
+
+	// Write the header even if there were no rows.
+	if err := cw.writeHeader(2, 0); err != nil {
+		return err
+	}
+
+	return cw.Flush()
+}
@@ -0,0 +1,245 @@
+package timeindex
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestLoadTimeSliceCsv(t *testing.T) {
+	data := `host,when,severity,message
+web1,2016-12-02 10:00:00,error,disk full
+web2,2016-12-02 08:00:00,info,started
+web1,2016-12-02 10:00:00,warning,"slow, very slow"
+`
+
+	options := CsvOptions{
+		HasHeader:   true,
+		TimeLayout:  "2006-01-02 15:04:05",
+		TimeColumn:  1,
+		ItemColumns: []int{0, 3},
+	}
+
+	ts, err := LoadTimeSliceCsv(strings.NewReader(data), options)
+	log.PanicIf(err)
+
+	if len(ts) != 2 {
+		t.Fatalf("Entry count not correct: (%d)", len(ts))
+	} else if ts[0].Time.Hour() != 8 || ts[1].Time.Hour() != 10 || ts[0].Time.Location() != time.UTC {
+		t.Fatalf("Times not correct: %v", ts)
+	} else if reflect.DeepEqual(ts[0].Items, []interface{}{[]string{"web2", "started"}}) == false {
+		t.Fatalf("First items not correct: %v", ts[0].Items)
+	} else if reflect.DeepEqual(ts[1].Items, []interface{}{[]string{"web1", "disk full"}, []string{"web1", "slow, very slow"}}) == false {
+		t.Fatalf("Second items not correct: %v", ts[1].Items)
+	}
+}
+
+func TestReadTimeSliceCsv_Streaming(t *testing.T) {
+	data := "2016-12-02T08:00:00Z;1\n2016-12-02T09:00:00Z;2\n2016-12-02T10:00:00Z;3\n"
+
+	options := CsvOptions{
+		Comma:       ';',
+		ItemColumns: []int{1},
+		ItemParser: func(fields []string) (interface{}, error) {
+			return strconv.Atoi(fields[0])
+		},
+	}
+
+	sum := 0
+	count := 0
+
+	cb := func(t time.Time, item interface{}) error {
+		sum += item.(int)
+		count++
+
+		return nil
+	}
+
+	err := ReadTimeSliceCsv(strings.NewReader(data), options, cb)
+	log.PanicIf(err)
+
+	if count != 3 || sum != 6 {
+		t.Fatalf("Rows not read correctly: (%d) (%d)", count, sum)
+	}
+}
+
+func TestReadTimeSliceCsv_BadTime(t *testing.T) {
+	err := ReadTimeSliceCsv(strings.NewReader("yesterday\n"), CsvOptions{}, func(t time.Time, item interface{}) error {
+		return nil
+	})
+
+	if err == nil {
+		t.Fatalf("Expected error for invalid time.")
+	}
+}
+
+func TestTimeSliceCsv_RoundTrip(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339Nano, "2016-12-02T08:05:44.5Z")
+	log.PanicIf(err)
+
+	time2, err := time.Parse(time.RFC3339, "2016-12-02T09:05:44Z")
+	log.PanicIf(err)
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(time1, []string{"a", "1"})
+	ts = ts.Add(time1, []string{"b", "2"})
+	ts = ts.Add(time2, nil)
+
+	options := CsvOptions{
+		HasHeader:   true,
+		Header:      []string{"time", "name", "value"},
+		ItemColumns: []int{1, 2},
+	}
+
+	b := new(bytes.Buffer)
+
+	err = WriteTimeSliceCsv(b, ts, options)
+	log.PanicIf(err)
+
+	expected := "time,name,value\n2016-12-02T08:05:44.5Z,a,1\n2016-12-02T08:05:44.5Z,b,2\n2016-12-02T09:05:44Z\n"
+	if b.String() != expected {
+		t.Fatalf("CSV not correct:\n%s", b.String())
+	}
+
+	recovered, err := LoadTimeSliceCsv(b, options)
+	log.PanicIf(err)
+
+	if len(recovered) != 2 {
+		t.Fatalf("Entry count not correct: (%d)", len(recovered))
+	}
+
+	for i, te := range ts {
+		if recovered[i].Time.Equal(te.Time) == false || reflect.DeepEqual(recovered[i].Items, te.Items) == false {
+			t.Fatalf("Entry (%d) not correct: %v != %v", i, recovered[i], te)
+		}
+	}
+}
+
+func TestTimeIntervalSliceCsv_RoundTrip(t *testing.T) {
+	left1, err := time.Parse(time.RFC3339, "2016-12-03T07:00:00Z")
+	log.PanicIf(err)
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(left1, left1.Add(time.Hour*2), 12)
+	tis = tis.Add(left1, left1.Add(time.Hour), 34)
+
+	options := CsvOptions{
+		FromColumn:  0,
+		ToColumn:    1,
+		ItemColumns: []int{2},
+		ItemParser: func(fields []string) (interface{}, error) {
+			return strconv.Atoi(fields[0])
+		},
+	}
+
+	b := new(bytes.Buffer)
+
+	err = WriteTimeIntervalSliceCsv(b, tis, options)
+	log.PanicIf(err)
+
+	expected := "2016-12-03T07:00:00Z,2016-12-03T08:00:00Z,34\n2016-12-03T07:00:00Z,2016-12-03T09:00:00Z,12\n"
+	if b.String() != expected {
+		t.Fatalf("CSV not correct:\n%s", b.String())
+	}
+
+	recovered, err := LoadTimeIntervalSliceCsv(b, options)
+	log.PanicIf(err)
+
+	if len(recovered) != 2 {
+		t.Fatalf("Interval count not correct: (%d)", len(recovered))
+	}
+
+	for i, ti := range tis {
+		if recovered[i].From.Equal(ti.From) == false || recovered[i].To.Equal(ti.To) == false || reflect.DeepEqual(recovered[i].Items, ti.Items) == false {
+			t.Fatalf("Interval (%d) not correct: %v != %v", i, recovered[i], ti)
+		}
+	}
+}
+
+func TestTimeSliceCsv_RoundTrip_DerivedHeader(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339, "2016-12-02T08:05:44Z")
+	log.PanicIf(err)
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(time1, []string{"a", "1"})
+	ts = ts.Add(time1.Add(time.Hour), []string{"b", "2"})
+
+	options := CsvOptions{
+		HasHeader:   true,
+		ItemColumns: []int{1, 2},
+	}
+
+	b := new(bytes.Buffer)
+
+	err = WriteTimeSliceCsv(b, ts, options)
+	log.PanicIf(err)
+
+	expected := "time,item1,item2\n2016-12-02T08:05:44Z,a,1\n2016-12-02T09:05:44Z,b,2\n"
+	if b.String() != expected {
+		t.Fatalf("CSV not correct:\n%s", b.String())
+	}
+
+	recovered, err := LoadTimeSliceCsv(b, options)
+	log.PanicIf(err)
+
+	if len(recovered) != len(ts) {
+		t.Fatalf("Entry count not correct: (%d)", len(recovered))
+	}
+
+	for i, te := range ts {
+		if recovered[i].Time.Equal(te.Time) == false || reflect.DeepEqual(recovered[i].Items, te.Items) == false {
+			t.Fatalf("Entry (%d) not correct: %v != %v", i, recovered[i], te)
+		}
+	}
+
+	// The header is written even without rows.
+
+	b = new(bytes.Buffer)
+
+	err = WriteTimeIntervalSliceCsv(b, TimeIntervalSlice{}, options)
+	log.PanicIf(err)
+
+	if b.String() != "from,to\n" {
+		t.Fatalf("CSV without rows not correct:\n%s", b.String())
+	}
+}
+
+func TestLoadTimeIntervalSliceCsv_Location(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	log.PanicIf(err)
+
+	data := "2016-12-03 07:00,2016-12-03 09:00\n"
+
+	options := CsvOptions{
+		TimeLayout: "2006-01-02 15:04",
+		Location:   location,
+		FromColumn: 0,
+		ToColumn:   1,
+	}
+
+	tis, err := LoadTimeIntervalSliceCsv(strings.NewReader(data), options)
+	log.PanicIf(err)
+
+	if len(tis) != 1 || tis[0].From.UTC().Hour() != 12 || len(tis[0].Items) != 0 {
+		t.Fatalf("Interval not correct: %v", tis)
+	}
+}
+
+func TestLoadTimeIntervalSliceCsv_Invalid(t *testing.T) {
+	data := "2016-12-03T09:00:00Z,2016-12-03T07:00:00Z\n"
+
+	options := CsvOptions{
+		FromColumn: 0,
+		ToColumn:   1,
+	}
+
+	_, err := LoadTimeIntervalSliceCsv(strings.NewReader(data), options)
+	if err == nil {
+		t.Fatalf("Expected error for inverted interval.")
+	}
+}
@@ -0,0 +1,169 @@
+package timeindex
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// DefaultSweepInterval is the default
+	// `ExpiringIntervalIndexOptions.SweepInterval`.
+	DefaultSweepInterval = time.Second
+)
+
+// ExpiringIntervalIndexOptions configures an `ExpiringIntervalIndex`.
+type ExpiringIntervalIndexOptions struct {
+	// Clock defaults to `DefaultClock`.
+	Clock Clock
+
+	// Grace is how long after its end an interval is kept.
+	Grace time.Duration
+
+	// SweepInterval is how often expired intervals are removed. Defaults to
+	// `DefaultSweepInterval`.
+	SweepInterval time.Duration
+
+	// OnExpire, if not nil, is called with every interval that expires. It is
+	// called from the sweeper goroutine (or from `Sweep`) without any lock
+	// held.
+	OnExpire func(ti TimeInterval)
+}
+
+// ExpiringIntervalIndex is a `TimeIntervalSlice` that is safe for concurrent
+// use and whose intervals are removed by a background goroutine once their
+// end time is more than the grace period in the past. Call `Close` to stop
+// the goroutine.
+type ExpiringIntervalIndex struct {
+	lock sync.RWMutex
+	tis  TimeIntervalSlice
+
+	options ExpiringIntervalIndexOptions
+
+	closeOnce sync.Once
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+func NewExpiringIntervalIndex(options ExpiringIntervalIndexOptions) *ExpiringIntervalIndex {
+	if options.Clock == nil {
+		options.Clock = DefaultClock
+	}
+
+	if options.SweepInterval <= 0 {
+		options.SweepInterval = DefaultSweepInterval
+	}
+
+	eii := &ExpiringIntervalIndex{
+		tis:     make(TimeIntervalSlice, 0),
+		options: options,
+		done:    make(chan struct{}),
+	}
+
+	eii.wg.Add(1)
+	go eii.sweeper()
+
+	return eii
+}
+
+func (eii *ExpiringIntervalIndex) sweeper() {
+	defer eii.wg.Done()
+
+	for {
+		timer := eii.options.Clock.NewTimer(eii.options.SweepInterval)
+
+		select {
+		case <-eii.done:
+			timer.Stop()
+			return
+		case <-timer.C():
+			eii.Sweep()
+		}
+	}
+}
+
+// Add adds the item with the given interval. An interval that has already
+// expired is removed by the next sweep.
+func (eii *ExpiringIntervalIndex) Add(from, to time.Time, data interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	eii.lock.Lock()
+	defer eii.lock.Unlock()
+
+	eii.tis = eii.tis.Add(from, to, data)
+
+	return nil
+}
+
+// Sweep removes the expired intervals now and returns how many there were.
+func (eii *ExpiringIntervalIndex) Sweep() int {
+	cutoff := eii.options.Clock.Now().Add(-eii.options.Grace)
+
+	eii.lock.Lock()
+
+	expired := make([]TimeInterval, 0)
+	kept := make(TimeIntervalSlice, 0, len(eii.tis))
+
+	for _, ti := range eii.tis {
+		if ti.To.Before(cutoff) == true {
+			expired = append(expired, ti)
+		} else {
+			kept = append(kept, ti)
+		}
+	}
+
+	if len(expired) > 0 {
+		eii.tis = kept
+	}
+
+	eii.lock.Unlock()
+
+	if eii.options.OnExpire != nil {
+		for _, ti := range expired {
+			eii.options.OnExpire(ti)
+		}
+	}
+
+	return len(expired)
+}
+
+// Search calls the callback with all intervals that contain the given time.
+// The callback must not modify the index.
+func (eii *ExpiringIntervalIndex) Search(t time.Time, cb func(ti TimeInterval) error) (err error) {
+	eii.lock.RLock()
+	defer eii.lock.RUnlock()
+
+	return eii.tis.Search(t, cb)
+}
+
+// Len returns the number of intervals.
+func (eii *ExpiringIntervalIndex) Len() int {
+	eii.lock.RLock()
+	defer eii.lock.RUnlock()
+
+	return len(eii.tis)
+}
+
+// Snapshot returns a copy of the intervals.
+func (eii *ExpiringIntervalIndex) Snapshot() TimeIntervalSlice {
+	eii.lock.RLock()
+	defer eii.lock.RUnlock()
+
+	return append(make(TimeIntervalSlice, 0, len(eii.tis)), eii.tis...)
+}
+
+// Close stops the sweeper. It may be called more than once.
+func (eii *ExpiringIntervalIndex) Close() (err error) {
+	eii.closeOnce.Do(func() {
+		close(eii.done)
+	})
+
+	eii.wg.Wait()
+
+	return nil
+}
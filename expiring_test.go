@@ -0,0 +1,114 @@
+package timeindex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestExpiringIntervalIndex(t *testing.T) {
+	start := getTestEpoch()
+	fc := NewFakeClock(start)
+
+	expired := make(chan TimeInterval, 10)
+
+	options := ExpiringIntervalIndexOptions{
+		Clock:         fc,
+		Grace:         time.Second * 30,
+		SweepInterval: time.Second * 10,
+		OnExpire: func(ti TimeInterval) {
+			expired <- ti
+		},
+	}
+
+	eii := NewExpiringIntervalIndex(options)
+	defer eii.Close()
+
+	err := eii.Add(start, start.Add(time.Minute), "short")
+	log.PanicIf(err)
+
+	err = eii.Add(start, start.Add(time.Hour), "long")
+	log.PanicIf(err)
+
+	// Advance in sweep-sized steps, waiting for the sweeper to go back to
+	// sleep after each one.
+	advance := func(steps int) {
+		for i := 0; i < steps; i++ {
+			fc.WaitForWaiters(1)
+			fc.Advance(options.SweepInterval)
+		}
+
+		fc.WaitForWaiters(1)
+	}
+
+	// One minute plus the grace period has not passed yet.
+	advance(9)
+
+	if eii.Len() != 2 || len(expired) != 0 {
+		t.Fatalf("Expected no expiry yet: (%d) (%d)", eii.Len(), len(expired))
+	}
+
+	advance(1)
+
+	if eii.Len() != 1 || len(expired) != 1 {
+		t.Fatalf("Expected one expiry: (%d) (%d)", eii.Len(), len(expired))
+	}
+
+	if ti := <-expired; ti.Items[0] != "short" {
+		t.Fatalf("Wrong interval expired: %v", ti)
+	}
+
+	matches := 0
+	cb := func(ti TimeInterval) error {
+		matches++
+		return nil
+	}
+
+	err = eii.Search(start.Add(time.Minute*30), cb)
+	log.PanicIf(err)
+
+	if matches != 1 {
+		t.Fatalf("Search not correct: (%d)", matches)
+	}
+}
+
+func TestExpiringIntervalIndex_Sweep(t *testing.T) {
+	start := getTestEpoch()
+	fc := NewFakeClock(start)
+
+	eii := NewExpiringIntervalIndex(ExpiringIntervalIndexOptions{Clock: fc, SweepInterval: time.Hour})
+	defer eii.Close()
+
+	err := eii.Add(start.Add(-time.Hour), start.Add(-time.Minute), "old")
+	log.PanicIf(err)
+
+	err = eii.Add(start.Add(-time.Hour), start.Add(time.Minute), "current")
+	log.PanicIf(err)
+
+	if count := eii.Sweep(); count != 1 {
+		t.Fatalf("Sweep count not correct: (%d)", count)
+	} else if snapshot := eii.Snapshot(); len(snapshot) != 1 || snapshot[0].Items[0] != "current" {
+		t.Fatalf("Remaining intervals not correct: %v", snapshot)
+	}
+}
+
+func TestExpiringIntervalIndex_Close(t *testing.T) {
+	fc := NewFakeClock(getTestEpoch())
+
+	eii := NewExpiringIntervalIndex(ExpiringIntervalIndexOptions{Clock: fc})
+
+	fc.WaitForWaiters(1)
+
+	err := eii.Close()
+	log.PanicIf(err)
+
+	// The sweeper's timer is stopped.
+	if len(fc.waiters) != 0 {
+		t.Fatalf("Timer not stopped: (%d)", len(fc.waiters))
+	}
+
+	// A second close is harmless.
+	err = eii.Close()
+	log.PanicIf(err)
+}
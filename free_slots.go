@@ -0,0 +1,227 @@
+package timeindex
+
+import (
+	"sort"
+	"time"
+)
+
+// BusinessHours limits free slots to the same hours on certain days, in a
+// given location.
+type BusinessHours struct {
+	// Location defaults to UTC.
+	Location *time.Location
+
+	// Start and End are the local (wall-clock) times of day, as offsets from
+	// midnight (e.g. 9 and 17 hours). End must be after Start.
+	Start time.Duration
+	End   time.Duration
+
+	// Weekdays defaults to Monday through Friday.
+	Weekdays []time.Weekday
+}
+
+func (bh *BusinessHours) location() *time.Location {
+	if bh.Location == nil {
+		return time.UTC
+	}
+
+	return bh.Location
+}
+
+func (bh *BusinessHours) isWorkday(weekday time.Weekday) bool {
+	if len(bh.Weekdays) == 0 {
+		return weekday != time.Saturday && weekday != time.Sunday
+	}
+
+	for _, workday := range bh.Weekdays {
+		if workday == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+// windows returns the business hours that overlap [from, to), clipped to it.
+func (bh *BusinessHours) windows(from, to time.Time) []TimeInterval {
+	location := bh.location()
+	windows := make([]TimeInterval, 0)
+
+	if bh.End <= bh.Start {
+		return windows
+	}
+
+	year, month, day := from.In(location).Date()
+
+	// Start a day early in case the hours of the previous day are still
+	// running.
+	for i := -1; ; i++ {
+		date := time.Date(year, month, day+i, 0, 0, 0, 0, location)
+		if date.Before(to) == false {
+			break
+		} else if bh.isWorkday(date.Weekday()) == false {
+			continue
+		}
+
+		// Using seconds (rather than adding a duration) keeps the times on
+		// the wall clock across DST transitions.
+		start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, int(bh.Start/time.Second), 0, location)
+		end := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, int(bh.End/time.Second), 0, location)
+
+		if start.Before(from) == true {
+			start = from
+		}
+
+		if end.After(to) == true {
+			end = to
+		}
+
+		if start.Before(end) == true {
+			windows = append(windows, TimeInterval{From: start, To: end})
+		}
+	}
+
+	return windows
+}
+
+// coveredUntil returns the index of the first interval that starts at or
+// after `t` and the time until which `t` is covered by the intervals before
+// it (`t` itself if it isn't covered). The intervals are only sorted by start,
+// so the ends of the earlier ones still have to be checked.
+func (tis TimeIntervalSlice) coveredUntil(t time.Time) (first int, cursor time.Time) {
+	first = sort.Search(len(tis), func(i int) bool {
+		return tis[i].From.Before(t) == false
+	})
+
+	cursor = t
+	for _, ti := range tis[:first] {
+		if ti.To.After(cursor) == true {
+			cursor = ti.To
+		}
+	}
+
+	return first, cursor
+}
+
+// gaps returns the periods of [from, to) that no interval covers. Intervals
+// are treated as half-open, so a gap can start when an interval ends.
+func (tis TimeIntervalSlice) gaps(from, to time.Time) []TimeInterval {
+	gaps := make([]TimeInterval, 0)
+
+	first, cursor := tis.coveredUntil(from)
+
+	for _, ti := range tis[first:] {
+		if ti.From.Before(to) == false || cursor.Before(to) == false {
+			break
+		} else if ti.To.After(cursor) == false {
+			continue
+		}
+
+		if ti.From.After(cursor) == true {
+			gaps = append(gaps, TimeInterval{From: cursor, To: ti.From})
+		}
+
+		cursor = ti.To
+	}
+
+	if cursor.Before(to) == true {
+		gaps = append(gaps, TimeInterval{From: cursor, To: to})
+	}
+
+	return gaps
+}
+
+// FindFreeSlots returns the periods of [from, to) that are at least
+// `minDuration` long and not covered by any of the (busy) intervals. If
+// `hours` is not nil, the slots are also within business hours.
+func (tis TimeIntervalSlice) FindFreeSlots(from, to time.Time, minDuration time.Duration, hours *BusinessHours) TimeIntervalSlice {
+	slots := make(TimeIntervalSlice, 0)
+
+	if from.Before(to) == false {
+		return slots
+	}
+
+	free := tis.gaps(from, to)
+
+	if hours != nil {
+		windows := hours.windows(from, to)
+
+		// Intersect the two sorted, non-overlapping lists.
+		intersection := make([]TimeInterval, 0)
+		for i, j := 0, 0; i < len(free) && j < len(windows); {
+			start, end := free[i].From, free[i].To
+			if windows[j].From.After(start) == true {
+				start = windows[j].From
+			}
+
+			if windows[j].To.Before(end) == true {
+				end = windows[j].To
+			}
+
+			if start.Before(end) == true {
+				intersection = append(intersection, TimeInterval{From: start, To: end})
+			}
+
+			if free[i].To.Before(windows[j].To) == true {
+				i++
+			} else {
+				j++
+			}
+		}
+
+		free = intersection
+	}
+
+	for _, slot := range free {
+		if slot.To.Sub(slot.From) >= minDuration {
+			slot.Items = []interface{}{}
+			slots = append(slots, slot)
+		}
+	}
+
+	return slots
+}
+
+// FirstFit returns the earliest slot of `duration` starting at or after
+// `after` that doesn't overlap any of the (busy) intervals and, if `hours` is
+// not nil, is within business hours. `found` is false if no slot can fit
+// (e.g. the duration is longer than the business hours). The gaps are checked
+// in order, so only the intervals up to the slot are visited.
+func (tis TimeIntervalSlice) FirstFit(after time.Time, duration time.Duration, hours *BusinessHours) (slot TimeInterval, found bool) {
+	first, cursor := tis.coveredUntil(after)
+
+	for i := first; i <= len(tis); i++ {
+		// Everything is free after the last interval ends, so only look
+		// until then plus enough time for at least a full week of business
+		// hours.
+		end := cursor.Add(duration).AddDate(0, 0, 8)
+		if i < len(tis) {
+			end = tis[i].From
+		}
+
+		if end.After(cursor) == true {
+			free := []TimeInterval{{From: cursor, To: end}}
+			if hours != nil {
+				free = hours.windows(cursor, end)
+			}
+
+			for _, ti := range free {
+				if ti.To.Sub(ti.From) >= duration {
+					slot = TimeInterval{
+						From:  ti.From,
+						To:    ti.From.Add(duration),
+						Items: []interface{}{},
+					}
+
+					return slot, true
+				}
+			}
+		}
+
+		if i < len(tis) && tis[i].To.After(cursor) == true {
+			cursor = tis[i].To
+		}
+	}
+
+	return slot, false
+}
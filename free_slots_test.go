@@ -0,0 +1,164 @@
+package timeindex
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeIntervalSlice_FindFreeSlots(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(at(4, 9, 0), at(4, 10, 0), "standup")
+	tis = tis.Add(at(4, 9, 30), at(4, 11, 0), "review")
+	tis = tis.Add(at(4, 11, 0), at(4, 11, 15), "call")
+	tis = tis.Add(at(4, 13, 0), at(4, 13, 20), "lunch")
+	tis = tis.Add(at(4, 15, 0), at(4, 18, 0), "workshop")
+
+	slots := tis.FindFreeSlots(at(4, 8, 0), at(4, 17, 0), time.Minute*30, nil)
+
+	expected := []TimeInterval{
+		{From: at(4, 8, 0), To: at(4, 9, 0)},
+		{From: at(4, 11, 15), To: at(4, 13, 0)},
+		{From: at(4, 13, 20), To: at(4, 15, 0)},
+	}
+
+	if len(slots) != len(expected) {
+		t.Fatalf("Slots not correct: %v", slots)
+	}
+
+	for i, slot := range slots {
+		if slot.From.Equal(expected[i].From) == false || slot.To.Equal(expected[i].To) == false {
+			t.Fatalf("Slot (%d) not correct: %v", i, slot)
+		}
+	}
+
+	// Longer slots only.
+	if slots := tis.FindFreeSlots(at(4, 8, 0), at(4, 17, 0), time.Hour*2, nil); len(slots) != 0 {
+		t.Fatalf("Expected no slots: %v", slots)
+	}
+
+	// Nothing busy.
+	if slots := (TimeIntervalSlice{}).FindFreeSlots(at(4, 8, 0), at(4, 17, 0), 0, nil); len(slots) != 1 || slots[0].To.Sub(slots[0].From) != time.Hour*9 {
+		t.Fatalf("Slots not correct: %v", slots)
+	}
+
+	if slots := tis.FindFreeSlots(at(4, 17, 0), at(4, 8, 0), 0, nil); len(slots) != 0 {
+		t.Fatalf("Expected no slots for empty range: %v", slots)
+	}
+}
+
+func TestTimeIntervalSlice_FindFreeSlots_BusinessHours(t *testing.T) {
+	location := getNewYork()
+
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2016, month, day, hour, 0, 0, 0, location)
+	}
+
+	hours := &BusinessHours{
+		Location: location,
+		Start:    time.Hour * 9,
+		End:      time.Hour * 17,
+	}
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(at(3, 11, 8), at(3, 11, 12), "busy")
+
+	// Friday through Tuesday, across the spring-forward change on Sunday.
+	slots := tis.FindFreeSlots(at(3, 11, 0), at(3, 16, 0), time.Hour, hours)
+
+	expected := []TimeInterval{
+		{From: at(3, 11, 12), To: at(3, 11, 17)},
+		{From: at(3, 14, 9), To: at(3, 14, 17)},
+		{From: at(3, 15, 9), To: at(3, 15, 17)},
+	}
+
+	if len(slots) != len(expected) {
+		t.Fatalf("Slots not correct: %v", slots)
+	}
+
+	for i, slot := range slots {
+		if slot.From.Equal(expected[i].From) == false || slot.To.Equal(expected[i].To) == false {
+			t.Fatalf("Slot (%d) not correct: %v", i, slot)
+		}
+	}
+
+	// Weekend hours.
+	hours.Weekdays = []time.Weekday{time.Sunday}
+
+	slots = tis.FindFreeSlots(at(3, 11, 0), at(3, 16, 0), time.Hour, hours)
+	if len(slots) != 1 || slots[0].From.Equal(at(3, 13, 9)) == false || slots[0].To.Sub(slots[0].From) != time.Hour*8 {
+		t.Fatalf("Slots not correct: %v", slots)
+	}
+}
+
+func TestTimeIntervalSlice_FirstFit(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(at(4, 9), at(4, 10), "a")
+	tis = tis.Add(at(4, 11), at(4, 16), "b")
+
+	slot, found := tis.FirstFit(at(4, 9), time.Hour, nil)
+	if found == false || slot.From.Equal(at(4, 10)) == false || slot.To.Equal(at(4, 11)) == false {
+		t.Fatalf("Slot not correct: %v", slot)
+	}
+
+	slot, found = tis.FirstFit(at(4, 9), time.Hour*2, nil)
+	if found == false || slot.From.Equal(at(4, 16)) == false {
+		t.Fatalf("Slot not correct: %v", slot)
+	}
+
+	hours := &BusinessHours{
+		Start: time.Hour * 9,
+		End:   time.Hour * 17,
+	}
+
+	// Monday is too busy, so the next business day.
+	slot, found = tis.FirstFit(at(4, 9), time.Hour*2, hours)
+	if found == false || slot.From.Equal(at(5, 9)) == false {
+		t.Fatalf("Slot not correct: %v", slot)
+	}
+
+	// From Friday evening to Monday morning.
+	slot, found = TimeIntervalSlice{}.FirstFit(at(8, 18), time.Hour, hours)
+	if found == false || slot.From.Equal(at(11, 9)) == false {
+		t.Fatalf("Slot not correct: %v", slot)
+	}
+
+	if _, found := tis.FirstFit(at(4, 9), time.Hour*9, hours); found == true {
+		t.Fatalf("Expected no slot longer than the business hours.")
+	}
+}
+
+func TestTimeIntervalSlice_FirstFit_Covered(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	// A long interval that started earlier still covers the search start,
+	// and a later one starts inside it.
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(at(1, 0), at(4, 12), "long")
+	tis = tis.Add(at(2, 0), at(2, 1), "inside")
+	tis = tis.Add(at(4, 13), at(4, 14), "later")
+
+	slot, found := tis.FirstFit(at(3, 0), time.Hour, nil)
+	if found == false || slot.From.Equal(at(4, 12)) == false {
+		t.Fatalf("Slot not correct: %v", slot)
+	}
+
+	slot, found = tis.FirstFit(at(3, 0), time.Hour*2, nil)
+	if found == false || slot.From.Equal(at(4, 14)) == false {
+		t.Fatalf("Slot not correct: %v", slot)
+	}
+
+	slots := tis.FindFreeSlots(at(3, 0), at(5, 0), time.Hour, nil)
+	if len(slots) != 2 || slots[0].From.Equal(at(4, 12)) == false || slots[1].From.Equal(at(4, 14)) == false {
+		t.Fatalf("Slots not correct: %v", slots)
+	}
+}
@@ -0,0 +1,332 @@
+package timeindex
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Items are gob-encoded individually along with the name that their type was
+// registered under (see `RegisterItemType`) so that decoding does not depend
+// on `gob.Register`. An item is encoded with its `GobEncode` method if it has
+// one, else its `MarshalBinary` method if it has one, else by gob.
+
+var (
+	ErrItemTypeNotRegistered = errors.New("item type not registered")
+)
+
+const (
+	// gobItemListTypeName identifies a nested []interface{}, whose elements
+	// are encoded as items themselves.
+	gobItemListTypeName = "[]interface{}"
+)
+
+var (
+	itemTypesLock   sync.RWMutex
+	itemTypesByName = make(map[string]reflect.Type)
+	itemTypeNames   = make(map[reflect.Type]string)
+)
+
+func init() {
+	builtins := []interface{}{
+		"", false, []byte{},
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0), complex64(0), complex128(0),
+		time.Time{}, time.Duration(0),
+		TimeSlice{}, TimeIntervalSlice{},
+	}
+
+	for _, value := range builtins {
+		RegisterItemType(reflect.TypeOf(value).String(), value)
+	}
+}
+
+// RegisterItemType registers the concrete type of `value` under the given
+// name so that items of that type can be gob-encoded. Registering a different
+// name or type than one already registered panics (as `gob.RegisterName`).
+func RegisterItemType(name string, value interface{}) {
+	if name == "" || name == gobItemListTypeName {
+		panic(fmt.Errorf("item-type name [%s] is reserved", name))
+	}
+
+	t := reflect.TypeOf(value)
+	if t == nil {
+		panic(fmt.Errorf("item type can not be nil"))
+	}
+
+	itemTypesLock.Lock()
+	defer itemTypesLock.Unlock()
+
+	if existing, found := itemTypesByName[name]; found == true && existing != t {
+		panic(fmt.Errorf("item-type name [%s] already registered for [%s]", name, existing))
+	} else if existing, found := itemTypeNames[t]; found == true && existing != name {
+		panic(fmt.Errorf("item type [%s] already registered as [%s]", t, existing))
+	}
+
+	itemTypesByName[name] = t
+	itemTypeNames[t] = name
+}
+
+type gobItem struct {
+	Type string
+	Data []byte
+}
+
+func encodeGobItem(item interface{}) (gi gobItem, err error) {
+	if item == nil {
+		return gi, nil
+	}
+
+	if list, ok := item.([]interface{}); ok == true {
+		encoded, err := encodeGobItems(list)
+		if err != nil {
+			return gi, err
+		}
+
+		b := new(bytes.Buffer)
+		if err := gob.NewEncoder(b).Encode(encoded); err != nil {
+			return gi, err
+		}
+
+		gi = gobItem{
+			Type: gobItemListTypeName,
+			Data: b.Bytes(),
+		}
+
+		return gi, nil
+	}
+
+	itemTypesLock.RLock()
+	name, found := itemTypeNames[reflect.TypeOf(item)]
+	itemTypesLock.RUnlock()
+
+	if found == false {
+		return gi, ErrItemTypeNotRegistered
+	}
+
+	var data []byte
+	if ge, ok := item.(gob.GobEncoder); ok == true {
+		data, err = ge.GobEncode()
+	} else if bm, ok := item.(encoding.BinaryMarshaler); ok == true {
+		data, err = bm.MarshalBinary()
+	} else {
+		b := new(bytes.Buffer)
+		err = gob.NewEncoder(b).Encode(item)
+		data = b.Bytes()
+	}
+
+	if err != nil {
+		return gi, err
+	}
+
+	gi = gobItem{
+		Type: name,
+		Data: data,
+	}
+
+	return gi, nil
+}
+
+func decodeGobItem(gi gobItem) (item interface{}, err error) {
+	if gi.Type == "" {
+		return nil, nil
+	} else if gi.Type == gobItemListTypeName {
+		encoded := make([]gobItem, 0)
+		if err := gob.NewDecoder(bytes.NewReader(gi.Data)).Decode(&encoded); err != nil {
+			return nil, err
+		}
+
+		return decodeGobItems(encoded)
+	}
+
+	itemTypesLock.RLock()
+	t, found := itemTypesByName[gi.Type]
+	itemTypesLock.RUnlock()
+
+	if found == false {
+		return nil, fmt.Errorf("item type [%s] not registered", gi.Type)
+	}
+
+	// The value that we decode into and the value that we return. These
+	// differ unless the registered type is a pointer.
+	var target, value reflect.Value
+	if t.Kind() == reflect.Ptr {
+		target = reflect.New(t.Elem())
+		value = target
+	} else {
+		target = reflect.New(t)
+		value = target.Elem()
+	}
+
+	if gd, ok := target.Interface().(gob.GobDecoder); ok == true && implementsGobEncoder(t) == true {
+		err = gd.GobDecode(gi.Data)
+	} else if bu, ok := target.Interface().(encoding.BinaryUnmarshaler); ok == true && implementsBinaryMarshaler(t) == true {
+		err = bu.UnmarshalBinary(gi.Data)
+	} else {
+		err = gob.NewDecoder(bytes.NewReader(gi.Data)).DecodeValue(target)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value.Interface(), nil
+}
+
+var (
+	gobEncoderType      = reflect.TypeOf((*gob.GobEncoder)(nil)).Elem()
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+)
+
+// implementsGobEncoder returns whether items of the given type were encoded
+// with `GobEncode`.
+func implementsGobEncoder(t reflect.Type) bool {
+	return t.Implements(gobEncoderType)
+}
+
+// implementsBinaryMarshaler returns whether items of the given type were
+// encoded with `MarshalBinary` (and not `GobEncode`).
+func implementsBinaryMarshaler(t reflect.Type) bool {
+	return t.Implements(gobEncoderType) == false && t.Implements(binaryMarshalerType)
+}
+
+func encodeGobItems(items []interface{}) (encoded []gobItem, err error) {
+	encoded = make([]gobItem, len(items))
+	for i, item := range items {
+		encoded[i], err = encodeGobItem(item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return encoded, nil
+}
+
+func decodeGobItems(encoded []gobItem) (items []interface{}, err error) {
+	items = make([]interface{}, len(encoded))
+	for i, gi := range encoded {
+		items[i], err = decodeGobItem(gi)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+type gobTimeEntry struct {
+	Time  time.Time
+	Items []gobItem
+}
+
+type gobTimeInterval struct {
+	From  time.Time
+	To    time.Time
+	Items []gobItem
+}
+
+// GobEncode encodes the slice and its items. Every item must be of a type
+// registered with `RegisterItemType`.
+func (ts TimeSlice) GobEncode() (data []byte, err error) {
+	encoded := make([]gobTimeEntry, len(ts))
+	for i, te := range ts {
+		items, err := encodeGobItems(te.Items)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded[i] = gobTimeEntry{
+			Time:  te.Time,
+			Items: items,
+		}
+	}
+
+	b := new(bytes.Buffer)
+	if err := gob.NewEncoder(b).Encode(encoded); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// GobDecode decodes a slice encoded by `GobEncode`.
+func (ts *TimeSlice) GobDecode(data []byte) (err error) {
+	encoded := make([]gobTimeEntry, 0)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&encoded); err != nil {
+		return err
+	}
+
+	decoded := make(TimeSlice, len(encoded))
+	for i, gte := range encoded {
+		items, err := decodeGobItems(gte.Items)
+		if err != nil {
+			return err
+		}
+
+		decoded[i] = TimeEntry{
+			Time:  gte.Time,
+			Items: items,
+		}
+	}
+
+	*ts = decoded
+
+	return nil
+}
+
+// GobEncode encodes the slice and its items. Every item must be of a type
+// registered with `RegisterItemType`.
+func (tis TimeIntervalSlice) GobEncode() (data []byte, err error) {
+	encoded := make([]gobTimeInterval, len(tis))
+	for i, ti := range tis {
+		items, err := encodeGobItems(ti.Items)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded[i] = gobTimeInterval{
+			From:  ti.From,
+			To:    ti.To,
+			Items: items,
+		}
+	}
+
+	b := new(bytes.Buffer)
+	if err := gob.NewEncoder(b).Encode(encoded); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// GobDecode decodes a slice encoded by `GobEncode`.
+func (tis *TimeIntervalSlice) GobDecode(data []byte) (err error) {
+	encoded := make([]gobTimeInterval, 0)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&encoded); err != nil {
+		return err
+	}
+
+	decoded := make(TimeIntervalSlice, len(encoded))
+	for i, gti := range encoded {
+		items, err := decodeGobItems(gti.Items)
+		if err != nil {
+			return err
+		}
+
+		decoded[i] = TimeInterval{
+			From:  gti.From,
+			To:    gti.To,
+			Items: items,
+		}
+	}
+
+	*tis = decoded
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package timeindex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+type testGobItem struct {
+	Name  string
+	Count int
+}
+
+// testBinaryItem is encoded via `encoding.BinaryMarshaler`.
+type testBinaryItem struct {
+	value uint32
+}
+
+func (tbi testBinaryItem) MarshalBinary() (data []byte, err error) {
+	data = make([]byte, 4)
+	binary.BigEndian.PutUint32(data, tbi.value)
+
+	return data, nil
+}
+
+func (tbi *testBinaryItem) UnmarshalBinary(data []byte) (err error) {
+	if len(data) != 4 {
+		return fmt.Errorf("binary item not valid")
+	}
+
+	tbi.value = binary.BigEndian.Uint32(data)
+
+	return nil
+}
+
+type testUnregisteredItem struct {
+	Name string
+}
+
+func init() {
+	RegisterItemType("testGobItem", testGobItem{})
+	RegisterItemType("*testGobItem", &testGobItem{})
+	RegisterItemType("testBinaryItem", testBinaryItem{})
+}
+
+func roundTripTestGob(value interface{}, recovered interface{}) {
+	b := new(bytes.Buffer)
+
+	err := gob.NewEncoder(b).Encode(value)
+	log.PanicIf(err)
+
+	err = gob.NewDecoder(b).Decode(recovered)
+	log.PanicIf(err)
+}
+
+func TestTimeSlice_GobRoundTrip(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339Nano, "2016-12-02T08:05:44.123456789Z")
+	log.PanicIf(err)
+
+	time2, err := time.Parse(time.RFC3339, "2016-12-02T09:05:44Z")
+	log.PanicIf(err)
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(time1, "a")
+	ts = ts.Add(time1, 12)
+	ts = ts.Add(time1, testGobItem{Name: "value", Count: 3})
+	ts = ts.Add(time2, &testGobItem{Name: "pointer", Count: 4})
+	ts = ts.Add(time2, testBinaryItem{value: 99})
+	ts = ts.Add(time2, time.Minute)
+
+	// A nil item can't be added, but it can be present.
+	ts[1].Items = append(ts[1].Items, nil)
+
+	var recovered TimeSlice
+	roundTripTestGob(ts, &recovered)
+
+	if len(recovered) != 2 {
+		t.Fatalf("Entry count not correct: (%d)", len(recovered))
+	} else if recovered[0].Time.Equal(time1) == false || recovered[1].Time.Equal(time2) == false {
+		t.Fatalf("Times not correct: %v", recovered)
+	}
+
+	for i, te := range ts {
+		if reflect.DeepEqual(recovered[i].Items, te.Items) == false {
+			t.Fatalf("Items of entry (%d) not correct: %v != %v", i, recovered[i].Items, te.Items)
+		}
+	}
+}
+
+func TestTimeSlice_GobRoundTrip_Nested(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339, "2016-12-02T08:05:44Z")
+	log.PanicIf(err)
+
+	inner := make(TimeSlice, 0)
+	inner = inner.Add(time1, testGobItem{Name: "inner"})
+
+	innerIntervals := make(TimeIntervalSlice, 0)
+	innerIntervals = innerIntervals.Add(time1, time1.Add(time.Hour), "interval")
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(time1, inner)
+	ts = ts.Add(time1, innerIntervals)
+	ts = ts.Add(time1, []interface{}{"list", nil, []interface{}{1.5, testGobItem{Name: "deep"}}})
+
+	var recovered TimeSlice
+	roundTripTestGob(ts, &recovered)
+
+	items := recovered[0].Items
+	if len(items) != 3 {
+		t.Fatalf("Item count not correct: (%d)", len(items))
+	}
+
+	recoveredInner := items[0].(TimeSlice)
+	if len(recoveredInner) != 1 || reflect.DeepEqual(recoveredInner[0].Items, []interface{}{testGobItem{Name: "inner"}}) == false {
+		t.Fatalf("Nested slice not correct: %v", recoveredInner)
+	}
+
+	recoveredIntervals := items[1].(TimeIntervalSlice)
+	if len(recoveredIntervals) != 1 || recoveredIntervals[0].To.Equal(time1.Add(time.Hour)) == false || reflect.DeepEqual(recoveredIntervals[0].Items, []interface{}{"interval"}) == false {
+		t.Fatalf("Nested interval slice not correct: %v", recoveredIntervals)
+	}
+
+	if reflect.DeepEqual(items[2], []interface{}{"list", nil, []interface{}{1.5, testGobItem{Name: "deep"}}}) == false {
+		t.Fatalf("Nested list not correct: %v", items[2])
+	}
+}
+
+func TestTimeSlice_GobEncode_Unregistered(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339, "2016-12-02T08:05:44Z")
+	log.PanicIf(err)
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(time1, testUnregisteredItem{Name: "x"})
+
+	_, err = ts.GobEncode()
+	if err != ErrItemTypeNotRegistered {
+		t.Fatalf("Expected unregistered error: %v", err)
+	}
+}
+
+func TestTimeIntervalSlice_GobRoundTrip(t *testing.T) {
+	left1, err := time.Parse(time.RFC3339, "2016-12-03T07:23:50Z")
+	log.PanicIf(err)
+
+	right1, err := time.Parse(time.RFC3339, "2016-12-04T07:23:50Z")
+	log.PanicIf(err)
+
+	right2, err := time.Parse(time.RFC3339, "2016-12-05T07:23:50Z")
+	log.PanicIf(err)
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(left1, right2, testGobItem{Name: "b"})
+	tis = tis.Add(left1, right1, "a")
+	tis = tis.Add(left1, right1, nil)
+
+	var recovered TimeIntervalSlice
+	roundTripTestGob(tis, &recovered)
+
+	if len(recovered) != 2 {
+		t.Fatalf("Interval count not correct: (%d)", len(recovered))
+	}
+
+	for i, ti := range tis {
+		if recovered[i].From.Equal(ti.From) == false || recovered[i].To.Equal(ti.To) == false {
+			t.Fatalf("Interval (%d) not correct: %v", i, recovered[i])
+		} else if reflect.DeepEqual(recovered[i].Items, ti.Items) == false {
+			t.Fatalf("Items of interval (%d) not correct: %v != %v", i, recovered[i].Items, ti.Items)
+		}
+	}
+}
+
+func TestRegisterItemType_Conflict(t *testing.T) {
+	defer func() {
+		if state := recover(); state == nil {
+			t.Fatalf("Expected panic for conflicting registration.")
+		}
+	}()
+
+	RegisterItemType("testGobItem", testUnregisteredItem{})
+}
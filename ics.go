@@ -0,0 +1,624 @@
+package timeindex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// DefaultIcsProduct is the default `IcsOptions.Product`.
+	DefaultIcsProduct = "-//dsoprea//go-time-index//EN"
+
+	// DefaultIcsInstantDuration is the default `IcsOptions.InstantDuration`.
+	DefaultIcsInstantDuration = time.Second
+)
+
+const (
+	icsUtcLayout     = "20060102T150405Z"
+	icsLocalLayout   = "20060102T150405"
+	icsDateLayout    = "20060102"
+	icsMaxLineLength = 75
+)
+
+var (
+	icsTextEscaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	icsTextUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+// CalendarEvent is an iCalendar VEVENT. It is the item of the intervals read
+// from .ics, and intervals whose items are `CalendarEvent`s are written with
+// their details.
+type CalendarEvent struct {
+	Uid         string
+	Summary     string
+	Description string
+	Location    string
+
+	// Start and End are the times of the event (or of the occurrence, for a
+	// recurring event).
+	Start time.Time
+	End   time.Time
+
+	// AllDay indicates that the event has dates rather than times.
+	AllDay bool
+
+	// Rule is the RRULE of a recurring event.
+	Rule string
+}
+
+// IcsOptions configures reading and writing iCalendar data.
+type IcsOptions struct {
+	// Location is used for floating times (without a zone) and dates.
+	// Defaults to UTC.
+	Location *time.Location
+
+	// From and To, if not zero, limit the events that are read to the ones
+	// that overlap [From, To]. They are required to read recurring events,
+	// which are expanded within them.
+	From time.Time
+	To   time.Time
+
+	// Product is written as the PRODID. Defaults to `DefaultIcsProduct`.
+	Product string
+
+	// Clock gives the DTSTAMP of the events that are written. Defaults to
+	// `DefaultClock`.
+	Clock Clock
+
+	// InstantDuration is the length given to events at a time that have no
+	// length (e.g. a DTSTART time without DTEND or DURATION), since intervals
+	// can't be empty. Defaults to `DefaultIcsInstantDuration`.
+	InstantDuration time.Duration
+
+	// SkipInvalidEvents skips events that can't be read as intervals (e.g.
+	// with a time that isn't valid, an unknown time zone, an RRULE outside of
+	// what `ParseRecurrence` supports, or an RRULE without a window) rather
+	// than failing the whole read.
+	SkipInvalidEvents bool
+}
+
+func (options IcsOptions) location() *time.Location {
+	if options.Location == nil {
+		return time.UTC
+	}
+
+	return options.Location
+}
+
+func (options IcsOptions) instantDuration() time.Duration {
+	if options.InstantDuration <= 0 {
+		return DefaultIcsInstantDuration
+	}
+
+	return options.InstantDuration
+}
+
+func (options IcsOptions) clock() Clock {
+	if options.Clock == nil {
+		return DefaultClock
+	}
+
+	return options.Clock
+}
+
+func (options IcsOptions) product() string {
+	if options.Product == "" {
+		return DefaultIcsProduct
+	}
+
+	return options.Product
+}
+
+// icsProperty is one (unfolded) content line.
+type icsProperty struct {
+	name       string
+	parameters map[string]string
+	value      string
+}
+
+// parseIcsProperty parses a content line like
+// `DTSTART;TZID="Europe/Berlin":20240305T020000`.
+func parseIcsProperty(line string) (p icsProperty, err error) {
+	// Find the colon that separates the value, skipping quoted parameter
+	// values.
+	colon := -1
+	quoted := false
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && quoted == false {
+			colon = i
+			break
+		}
+	}
+
+	if colon == -1 {
+		return p, fmt.Errorf("iCalendar line not valid: [%s]", line)
+	}
+
+	p.value = line[colon+1:]
+	p.parameters = make(map[string]string)
+
+	parts := strings.Split(line[:colon], ";")
+	p.name = strings.ToUpper(parts[0])
+
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return p, fmt.Errorf("iCalendar parameter not valid: [%s]", line)
+		}
+
+		p.parameters[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+
+	return p, nil
+}
+
+// readIcsLines calls the callback with each unfolded content line.
+func readIcsLines(r io.Reader, cb func(line string) error) (err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	current := ""
+	hasCurrent := false
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		// Lines that start with whitespace continue the previous line.
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			current += line[1:]
+			continue
+		}
+
+		if hasCurrent == true {
+			if err := cb(current); err != nil {
+				return err
+			}
+		}
+
+		current = line
+		hasCurrent = line != ""
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if hasCurrent == true {
+		return cb(current)
+	}
+
+	return nil
+}
+
+// parseIcsTimes parses the (possibly comma-separated) times of a property.
+func (options IcsOptions) parseIcsTimes(p icsProperty) (times []time.Time, isDate bool, err error) {
+	location := options.location()
+	if tzid, found := p.parameters["TZID"]; found == true {
+		if location, err = time.LoadLocation(tzid); err != nil {
+			return nil, false, fmt.Errorf("iCalendar time zone not found: [%s]", tzid)
+		}
+	}
+
+	isDate = strings.ToUpper(p.parameters["VALUE"]) == "DATE"
+
+	for _, value := range strings.Split(p.value, ",") {
+		var t time.Time
+
+		if isDate == true {
+			t, err = time.ParseInLocation(icsDateLayout, value, location)
+		} else if strings.HasSuffix(value, "Z") == true {
+			t, err = time.Parse(icsUtcLayout, value)
+		} else {
+			t, err = time.ParseInLocation(icsLocalLayout, value, location)
+		}
+
+		if err != nil {
+			return nil, false, fmt.Errorf("iCalendar time not valid: [%s]", value)
+		}
+
+		times = append(times, t)
+	}
+
+	return times, isDate, nil
+}
+
+// icsEvent accumulates the properties of a VEVENT.
+type icsEvent struct {
+	event    CalendarEvent
+	hasEnd   bool
+	duration *IsoDuration
+	exDates  []time.Time
+
+	// err is the first property that couldn't be read. It is reported (or
+	// the event skipped) when the event ends.
+	err error
+}
+
+func (options IcsOptions) addIcsProperty(ie *icsEvent, p icsProperty) (err error) {
+	switch p.name {
+	case "UID":
+		ie.event.Uid = p.value
+	case "SUMMARY":
+		ie.event.Summary = icsTextUnescaper.Replace(p.value)
+	case "DESCRIPTION":
+		ie.event.Description = icsTextUnescaper.Replace(p.value)
+	case "LOCATION":
+		ie.event.Location = icsTextUnescaper.Replace(p.value)
+	case "RRULE":
+		ie.event.Rule = p.value
+	case "DTSTART", "DTEND", "EXDATE":
+		times, isDate, err := options.parseIcsTimes(p)
+		if err != nil {
+			return err
+		}
+
+		if p.name == "EXDATE" {
+			ie.exDates = append(ie.exDates, times...)
+		} else if p.name == "DTSTART" {
+			ie.event.Start = times[0]
+			ie.event.AllDay = isDate
+		} else {
+			ie.event.End = times[0]
+			ie.hasEnd = true
+		}
+	case "DURATION":
+		d, err := ParseIsoDuration(p.value)
+		if err != nil {
+			return err
+		}
+
+		ie.duration = &d
+	}
+
+	return nil
+}
+
+// icsOccurrences returns the event (or every occurrence of a recurring
+// event) that overlaps the window.
+func (options IcsOptions) icsOccurrences(ie *icsEvent) (occurrences []CalendarEvent, err error) {
+	event := ie.event
+
+	if ie.err != nil {
+		return nil, ie.err
+	} else if event.Start.IsZero() == true {
+		return nil, fmt.Errorf("iCalendar event has no DTSTART: [%s]", event.Uid)
+	} else if event.Rule != "" && (options.From.IsZero() == true || options.To.IsZero() == true) {
+		return nil, fmt.Errorf("iCalendar event recurs but no window was given: [%s]", event.Uid)
+	}
+
+	// Without an end, an event on a date lasts that day and an event at a
+	// time is instantaneous.
+	if ie.hasEnd == false {
+		if ie.duration != nil {
+			event.End = ie.duration.AddTo(event.Start, event.Start.Location())
+		} else if event.AllDay == true {
+			event.End = event.Start.AddDate(0, 0, 1)
+		} else {
+			event.End = event.Start
+		}
+	}
+
+	// Intervals can't be empty, so instantaneous events are given a length.
+	// This also covers recurring events, whose occurrences all have the same
+	// length.
+	if event.End.Before(event.Start) == true {
+		return nil, fmt.Errorf("iCalendar event ends before it starts: [%s]", event.Uid)
+	} else if event.End.Equal(event.Start) == true {
+		if event.AllDay == true {
+			return nil, fmt.Errorf("iCalendar event has no length: [%s]", event.Uid)
+		}
+
+		event.End = event.Start.Add(options.instantDuration())
+	}
+
+	if event.Rule == "" {
+		if event.End.Before(options.From) == true || (options.To.IsZero() == false && event.Start.After(options.To) == true) {
+			return nil, nil
+		}
+
+		return []CalendarEvent{event}, nil
+	}
+
+	r, err := ParseRecurrence(event.Rule, event.Start, event.End.Sub(event.Start))
+	if err != nil {
+		return nil, err
+	}
+
+	r.ExDates = append(r.ExDates, ie.exDates...)
+
+	for _, ti := range r.Expand(options.From, options.To) {
+		occurrence := event
+		occurrence.Start = ti.From
+		occurrence.End = ti.To
+
+		occurrences = append(occurrences, occurrence)
+	}
+
+	return occurrences, nil
+}
+
+// emitIcsEvent calls the callback with the event (or with every occurrence of
+// a recurring event).
+func (options IcsOptions) emitIcsEvent(ie *icsEvent, cb func(from, to time.Time, event CalendarEvent) error) (err error) {
+	occurrences, err := options.icsOccurrences(ie)
+	if err != nil {
+		if options.SkipInvalidEvents == true {
+			return nil
+		}
+
+		return err
+	}
+
+	for _, occurrence := range occurrences {
+		if err := cb(occurrence.Start, occurrence.End, occurrence); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadTimeIntervalSliceIcs calls the callback with the interval of each
+// VEVENT (or of each occurrence of a recurring VEVENT) without holding the
+// events in memory. DTEND or DURATION give the end. TZID parameters must be
+// IANA time-zone names; VTIMEZONE definitions are ignored. Modified
+// occurrences (RECURRENCE-ID) are read as separate events.
+func ReadTimeIntervalSliceIcs(r io.Reader, options IcsOptions, cb func(from, to time.Time, event CalendarEvent) error) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	// The components that we're nested in.
+	components := make([]string, 0)
+
+	var current *icsEvent
+
+	lineCb := func(line string) error {
+		p, err := parseIcsProperty(line)
+		if err != nil {
+			return err
+		}
+
+		switch p.name {
+		case "BEGIN":
+			components = append(components, strings.ToUpper(p.value))
+
+			if components[len(components)-1] == "VEVENT" {
+				current = new(icsEvent)
+			}
+
+			return nil
+		case "END":
+			if len(components) == 0 || components[len(components)-1] != strings.ToUpper(p.value) {
+				return fmt.Errorf("iCalendar component not closed correctly: [%s]", line)
+			}
+
+			components = components[:len(components)-1]
+
+			if strings.ToUpper(p.value) == "VEVENT" {
+				ie := current
+				current = nil
+
+				return options.emitIcsEvent(ie, cb)
+			}
+
+			return nil
+		}
+
+		// Only the properties of the event itself (not of, e.g., its alarms).
+		if current != nil && components[len(components)-1] == "VEVENT" {
+			if err := options.addIcsProperty(current, p); err != nil && current.err == nil {
+				current.err = err
+			}
+		}
+
+		return nil
+	}
+
+	err = readIcsLines(r, lineCb)
+	log.PanicIf(err)
+
+	if len(components) != 0 {
+		log.Panicf("iCalendar component not closed: [%s]", components[len(components)-1])
+	}
+
+	return nil
+}
+
+// LoadTimeIntervalSliceIcs loads a `TimeIntervalSlice` from iCalendar data.
+// The item of each interval is its `CalendarEvent`.
+func LoadTimeIntervalSliceIcs(r io.Reader, options IcsOptions) (tis TimeIntervalSlice, err error) {
+	tis = make(TimeIntervalSlice, 0)
+
+	cb := func(from, to time.Time, event CalendarEvent) error {
+		ti := TimeInterval{
+			From:  from,
+			To:    to,
+			Items: []interface{}{event},
+		}
+
+		tis = append(tis, ti)
+
+		return nil
+	}
+
+	if err := ReadTimeIntervalSliceIcs(r, options, cb); err != nil {
+		return nil, err
+	}
+
+	return sortAndCombineTimeIntervalSlice(tis), nil
+}
+
+// IcsWriter writes intervals as the VEVENTs of a VCALENDAR. Times are written
+// in UTC (dates for all-day `CalendarEvent`s). Call `Close` to end the
+// calendar.
+type IcsWriter struct {
+	w             io.Writer
+	options       IcsOptions
+	headerWritten bool
+	written       int
+}
+
+func NewIcsWriter(w io.Writer, options IcsOptions) *IcsWriter {
+	return &IcsWriter{
+		w:       w,
+		options: options,
+	}
+}
+
+// writeLine writes a content line, folded to 75 octets.
+func (iw *IcsWriter) writeLine(line string) (err error) {
+	b := make([]byte, 0, len(line)+len(line)/icsMaxLineLength*3+2)
+
+	lineLength := 0
+	for _, c := range line {
+		size := len(string(c))
+
+		if lineLength+size > icsMaxLineLength {
+			b = append(b, "\r\n "...)
+			lineLength = 1
+		}
+
+		b = append(b, string(c)...)
+		lineLength += size
+	}
+
+	b = append(b, "\r\n"...)
+
+	_, err = iw.w.Write(b)
+	return err
+}
+
+func (iw *IcsWriter) writeLines(lines ...string) (err error) {
+	for _, line := range lines {
+		if err := iw.writeLine(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (iw *IcsWriter) writeHeader() (err error) {
+	if iw.headerWritten == true {
+		return nil
+	}
+
+	iw.headerWritten = true
+
+	return iw.writeLines(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:"+iw.options.product())
+}
+
+func (iw *IcsWriter) writeEvent(from, to time.Time, event CalendarEvent) (err error) {
+	iw.written++
+
+	uid := event.Uid
+	if uid == "" {
+		uid = fmt.Sprintf("%s-%d@go-time-index", from.UTC().Format(icsUtcLayout), iw.written)
+	} else if event.Rule != "" {
+		// Each occurrence of a recurring event is written as its own event.
+		uid = fmt.Sprintf("%s-%s", uid, from.UTC().Format(icsUtcLayout))
+	}
+
+	lines := []string{
+		"BEGIN:VEVENT",
+		"UID:" + uid,
+		"DTSTAMP:" + iw.options.clock().Now().UTC().Format(icsUtcLayout),
+	}
+
+	if event.AllDay == true {
+		location := event.Start.Location()
+
+		lines = append(lines,
+			"DTSTART;VALUE=DATE:"+from.In(location).Format(icsDateLayout),
+			"DTEND;VALUE=DATE:"+to.In(location).Format(icsDateLayout))
+	} else {
+		lines = append(lines,
+			"DTSTART:"+from.UTC().Format(icsUtcLayout),
+			"DTEND:"+to.UTC().Format(icsUtcLayout))
+	}
+
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{"SUMMARY", event.Summary},
+		{"DESCRIPTION", event.Description},
+		{"LOCATION", event.Location},
+	} {
+		if field.value != "" {
+			lines = append(lines, field.name+":"+icsTextEscaper.Replace(field.value))
+		}
+	}
+
+	lines = append(lines, "END:VEVENT")
+
+	return iw.writeLines(lines...)
+}
+
+// WriteInterval writes one event per item of the interval (or one event
+// without a summary if there are no items). `CalendarEvent` items provide the
+// details of the event; other items are written as the summary using
+// `fmt.Sprint`.
+func (iw *IcsWriter) WriteInterval(ti TimeInterval) (err error) {
+	if err := iw.writeHeader(); err != nil {
+		return err
+	}
+
+	if len(ti.Items) == 0 {
+		return iw.writeEvent(ti.From, ti.To, CalendarEvent{})
+	}
+
+	for _, item := range ti.Items {
+		var event CalendarEvent
+
+		switch item.(type) {
+		case CalendarEvent:
+			event = item.(CalendarEvent)
+		case *CalendarEvent:
+			event = *item.(*CalendarEvent)
+		default:
+			event = CalendarEvent{Summary: fmt.Sprint(item)}
+		}
+
+		if err := iw.writeEvent(ti.From, ti.To, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close ends the calendar. It does not close the underlying writer.
+func (iw *IcsWriter) Close() (err error) {
+	if err := iw.writeHeader(); err != nil {
+		return err
+	}
+
+	return iw.writeLine("END:VCALENDAR")
+}
+
+// WriteTimeIntervalSliceIcs writes the slice as an iCalendar VCALENDAR.
+func WriteTimeIntervalSliceIcs(w io.Writer, tis TimeIntervalSlice, options IcsOptions) (err error) {
+	iw := NewIcsWriter(w, options)
+
+	for _, ti := range tis {
+		if err := iw.WriteInterval(ti); err != nil {
+			return err
+		}
+	}
+
+	return iw.Close()
+}
@@ -0,0 +1,354 @@
+package timeindex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	testIcs = "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//Vendor//Calendar//EN\r\n" +
+		"BEGIN:VTIMEZONE\r\n" +
+		"TZID:Europe/Berlin\r\n" +
+		"BEGIN:STANDARD\r\n" +
+		"DTSTART:19701025T030000\r\n" +
+		"END:STANDARD\r\n" +
+		"END:VTIMEZONE\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:maintenance@vendor\r\n" +
+		"DTSTART;TZID=\"Europe/Berlin\":20240305T020000\r\n" +
+		"DTEND;TZID=Europe/Berlin:20240305T040000\r\n" +
+		"RRULE:FREQ=WEEKLY;BYDAY=TU\r\n" +
+		"EXDATE;TZID=Europe/Berlin:20240319T020000\r\n" +
+		"SUMMARY:Database maintenance\r\n" +
+		"DESCRIPTION:Expect short outages\\, retries\\; and\\nfailovers.\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"TRIGGER:-PT15M\r\n" +
+		"DESCRIPTION:Alarm\r\n" +
+		"END:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:holiday@vendor\r\n" +
+		"DTSTART;VALUE=DATE:20240329\r\n" +
+		"SUMMARY:Good\r\n" +
+		"  Friday\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:call@vendor\r\n" +
+		"DTSTART:20240311T150000Z\r\n" +
+		"DURATION:PT30M\r\n" +
+		"LOCATION:Room 1\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:old@vendor\r\n" +
+		"DTSTART:20230101T150000Z\r\n" +
+		"DTEND:20230101T160000Z\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+)
+
+func TestLoadTimeIntervalSliceIcs(t *testing.T) {
+	berlin := getBerlin()
+
+	options := IcsOptions{
+		From: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC),
+	}
+
+	tis, err := LoadTimeIntervalSliceIcs(strings.NewReader(testIcs), options)
+	log.PanicIf(err)
+
+	// Five Tuesdays (one excluded), the call, and the holiday.
+	if len(tis) != 6 {
+		t.Fatalf("Interval count not correct: (%d) %v", len(tis), tis)
+	}
+
+	maintenance := tis[0].Items[0].(CalendarEvent)
+	if tis[0].From.Equal(time.Date(2024, 3, 5, 2, 0, 0, 0, berlin)) == false || tis[0].To.Sub(tis[0].From) != time.Hour*2 {
+		t.Fatalf("First occurrence not correct: %v", tis[0])
+	} else if maintenance.Summary != "Database maintenance" || maintenance.Description != "Expect short outages, retries; and\nfailovers." || maintenance.Rule != "FREQ=WEEKLY;BYDAY=TU" {
+		t.Fatalf("Event not correct: %#v", maintenance)
+	} else if maintenance.Start.Equal(tis[0].From) == false {
+		t.Fatalf("Event start not the occurrence: [%s]", maintenance.Start)
+	}
+
+	call := tis[1].Items[0].(CalendarEvent)
+	if call.Uid != "call@vendor" || call.Location != "Room 1" || tis[1].To.Sub(tis[1].From) != time.Minute*30 {
+		t.Fatalf("Call not correct: %v", tis[1])
+	}
+
+	if tis[2].From.Equal(time.Date(2024, 3, 12, 2, 0, 0, 0, berlin)) == false || tis[3].From.Equal(time.Date(2024, 3, 26, 2, 0, 0, 0, berlin)) == false {
+		t.Fatalf("Occurrences not correct: %v %v", tis[2], tis[3])
+	}
+
+	holiday := tis[4].Items[0].(CalendarEvent)
+	if holiday.Summary != "Good Friday" || holiday.AllDay == false || tis[4].To.Sub(tis[4].From) != time.Hour*24 {
+		t.Fatalf("Holiday not correct: %v", tis[4])
+	}
+
+	// After the change to summer time.
+	if tis[5].From.Equal(time.Date(2024, 4, 2, 2, 0, 0, 0, berlin)) == false || tis[5].From.UTC().Hour() != 0 {
+		t.Fatalf("Last occurrence not correct: %v", tis[5])
+	}
+}
+
+func TestReadTimeIntervalSliceIcs_NeedsWindow(t *testing.T) {
+	cb := func(from, to time.Time, event CalendarEvent) error {
+		return nil
+	}
+
+	if err := ReadTimeIntervalSliceIcs(strings.NewReader(testIcs), IcsOptions{}, cb); err == nil {
+		t.Fatalf("Expected error for recurring event without a window.")
+	}
+}
+
+func TestReadTimeIntervalSliceIcs_Floating(t *testing.T) {
+	location := getNewYork()
+
+	data := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240301T090000\nDTEND:20240301T100000\nEND:VEVENT\nEND:VCALENDAR\n"
+
+	tis, err := LoadTimeIntervalSliceIcs(strings.NewReader(data), IcsOptions{Location: location})
+	log.PanicIf(err)
+
+	if len(tis) != 1 || tis[0].From.Equal(time.Date(2024, 3, 1, 9, 0, 0, 0, location)) == false {
+		t.Fatalf("Intervals not correct: %v", tis)
+	}
+}
+
+func TestReadTimeIntervalSliceIcs_Invalid(t *testing.T) {
+	cb := func(from, to time.Time, event CalendarEvent) error {
+		return nil
+	}
+
+	invalid := []string{
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240301T090000Z\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240301T090000Z\n",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:No start\nEND:VEVENT\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;TZID=Mars/Olympus:20240301T090000\nEND:VEVENT\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240301T090000Z\nDTEND:20240301T080000Z\nEND:VEVENT\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nno colon\nEND:VCALENDAR\n",
+	}
+
+	for _, data := range invalid {
+		if err := ReadTimeIntervalSliceIcs(strings.NewReader(data), IcsOptions{}, cb); err == nil {
+			t.Fatalf("Expected error for:\n%s", data)
+		}
+	}
+}
+
+func TestReadTimeIntervalSliceIcs_InstantEvents(t *testing.T) {
+	data := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\nUID:instant\nDTSTART:20240301T090000Z\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:zero\nDTSTART:20240301T100000Z\nDTEND:20240301T100000Z\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:recurring\nDTSTART:20240301T110000Z\nDURATION:PT0S\nRRULE:FREQ=DAILY;COUNT=2\nEND:VEVENT\n" +
+		"END:VCALENDAR\n"
+
+	options := IcsOptions{
+		From: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC),
+	}
+
+	tis, err := LoadTimeIntervalSliceIcs(strings.NewReader(data), options)
+	log.PanicIf(err)
+
+	if len(tis) != 4 {
+		t.Fatalf("Interval count not correct: (%d)", len(tis))
+	}
+
+	for _, ti := range tis {
+		if ti.To.Sub(ti.From) != DefaultIcsInstantDuration {
+			t.Fatalf("Interval not correct: %v", ti)
+		}
+	}
+
+	options.InstantDuration = time.Minute
+
+	tis, err = LoadTimeIntervalSliceIcs(strings.NewReader(data), options)
+	log.PanicIf(err)
+
+	if len(tis) != 4 || tis[0].To.Sub(tis[0].From) != time.Minute {
+		t.Fatalf("Intervals not correct: %v", tis)
+	} else if tis[0].Items[0].(CalendarEvent).Uid != "instant" {
+		t.Fatalf("First event not correct: %v", tis[0])
+	}
+}
+
+func TestReadTimeIntervalSliceIcs_SkipInvalidEvents(t *testing.T) {
+	data := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\nUID:empty-day\nDTSTART;VALUE=DATE:20240301\nDTEND;VALUE=DATE:20240301\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:recurring\nDTSTART:20240301T110000Z\nDTEND:20240301T120000Z\nRRULE:FREQ=DAILY\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:bad-zone\nDTSTART;TZID=Mars/Olympus:20240301T090000\nDTEND:20240301T100000Z\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:valid\nDTSTART:20240301T120000Z\nDTEND:20240301T130000Z\nEND:VEVENT\n" +
+		"END:VCALENDAR\n"
+
+	// Without a window, the recurring event is invalid too.
+	options := IcsOptions{}
+
+	// Each of the invalid events fails the read on its own.
+	for _, uid := range []string{"empty-day", "recurring", "bad-zone"} {
+		single := "BEGIN:VCALENDAR\n" + data[strings.Index(data, "BEGIN:VEVENT\nUID:"+uid):]
+		single = single[:strings.Index(single, "END:VEVENT\n")] + "END:VEVENT\nEND:VCALENDAR\n"
+
+		if _, err := LoadTimeIntervalSliceIcs(strings.NewReader(single), options); err == nil {
+			t.Fatalf("Expected error for event [%s].", uid)
+		}
+	}
+
+	options.SkipInvalidEvents = true
+
+	tis, err := LoadTimeIntervalSliceIcs(strings.NewReader(data), options)
+	log.PanicIf(err)
+
+	if len(tis) != 1 || tis[0].Items[0].(CalendarEvent).Uid != "valid" {
+		t.Fatalf("Intervals not correct: %v", tis)
+	}
+
+	// The intervals that were read can be encoded and decoded again.
+	text, err := tis[0].MarshalText()
+	log.PanicIf(err)
+
+	var recovered TimeInterval
+
+	err = recovered.UnmarshalText(text)
+	log.PanicIf(err)
+}
+
+func TestReadTimeIntervalSliceIcs_VendorRules(t *testing.T) {
+	data := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\nUID:weekly\nDTSTART:20240301T090000Z\nDTEND:20240301T100000Z\nRRULE:FREQ=WEEKLY;WKST=MO;BYDAY=FR\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:unsupported\nDTSTART:20240301T090000Z\nDTEND:20240301T100000Z\nRRULE:FREQ=MONTHLY;BYDAY=1FR\nEND:VEVENT\n" +
+		"END:VCALENDAR\n"
+
+	options := IcsOptions{
+		From: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	if _, err := LoadTimeIntervalSliceIcs(strings.NewReader(data), options); err == nil {
+		t.Fatalf("Expected error for unsupported rule.")
+	}
+
+	options.SkipInvalidEvents = true
+
+	tis, err := LoadTimeIntervalSliceIcs(strings.NewReader(data), options)
+	log.PanicIf(err)
+
+	// Every Friday in March.
+	if len(tis) != 5 || tis[0].Items[0].(CalendarEvent).Uid != "weekly" {
+		t.Fatalf("Intervals not correct: %v", tis)
+	}
+}
+
+func TestWriteTimeIntervalSliceIcs(t *testing.T) {
+	fc := NewFakeClock(time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC))
+
+	berlin := getBerlin()
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(time.Date(2024, 3, 5, 2, 0, 0, 0, berlin), time.Date(2024, 3, 5, 4, 0, 0, 0, berlin), CalendarEvent{
+		Uid:         "maintenance",
+		Summary:     "Database maintenance",
+		Description: "Expect short outages, retries; and\nfailovers. " + strings.Repeat("More details. ", 5),
+	})
+
+	tis = tis.Add(time.Date(2024, 3, 29, 0, 0, 0, 0, berlin), time.Date(2024, 3, 30, 0, 0, 0, 0, berlin), &CalendarEvent{
+		Summary: "Good Friday",
+		Start:   time.Date(2024, 3, 29, 0, 0, 0, 0, berlin),
+		AllDay:  true,
+	})
+
+	tis = tis.Add(time.Date(2024, 3, 11, 15, 0, 0, 0, time.UTC), time.Date(2024, 3, 11, 15, 30, 0, 0, time.UTC), "on-call: alice")
+
+	b := new(bytes.Buffer)
+
+	err := WriteTimeIntervalSliceIcs(b, tis, IcsOptions{Clock: fc})
+	log.PanicIf(err)
+
+	expected := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//dsoprea//go-time-index//EN\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:maintenance\r\n" +
+		"DTSTAMP:20240201T120000Z\r\n" +
+		"DTSTART:20240305T010000Z\r\n" +
+		"DTEND:20240305T030000Z\r\n" +
+		"SUMMARY:Database maintenance\r\n" +
+		"DESCRIPTION:Expect short outages\\, retries\\; and\\nfailovers. More details. \r\n" +
+		" More details. More details. More details. More details. \r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:20240311T150000Z-2@go-time-index\r\n" +
+		"DTSTAMP:20240201T120000Z\r\n" +
+		"DTSTART:20240311T150000Z\r\n" +
+		"DTEND:20240311T153000Z\r\n" +
+		"SUMMARY:on-call: alice\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:20240328T230000Z-3@go-time-index\r\n" +
+		"DTSTAMP:20240201T120000Z\r\n" +
+		"DTSTART;VALUE=DATE:20240329\r\n" +
+		"DTEND;VALUE=DATE:20240330\r\n" +
+		"SUMMARY:Good Friday\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	if b.String() != expected {
+		t.Fatalf("iCalendar not correct:\n%s", b.String())
+	}
+
+	for _, line := range strings.Split(b.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("Line not folded: [%s]", line)
+		}
+	}
+
+	// Read it back.
+	recovered, err := LoadTimeIntervalSliceIcs(b, IcsOptions{Location: berlin})
+	log.PanicIf(err)
+
+	if len(recovered) != 3 {
+		t.Fatalf("Recovered intervals not correct: %v", recovered)
+	}
+
+	for i, ti := range recovered {
+		event := ti.Items[0].(CalendarEvent)
+		if ti.From.Equal(tis[i].From) == false || ti.To.Equal(tis[i].To) == false {
+			t.Fatalf("Recovered interval (%d) not correct: %v", i, ti)
+		} else if i == 0 && event.Description != tis[0].Items[0].(CalendarEvent).Description {
+			t.Fatalf("Recovered description not correct: [%s]", event.Description)
+		}
+	}
+}
+
+func TestIcsWriter_FoldsMultibyte(t *testing.T) {
+	b := new(bytes.Buffer)
+	iw := NewIcsWriter(b, IcsOptions{})
+
+	err := iw.writeLine("SUMMARY:" + strings.Repeat("é", 100))
+	log.PanicIf(err)
+
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("Line not folded: [%s]", line)
+		}
+	}
+
+	// Unfolding restores it.
+	lines := make([]string, 0)
+	err = readIcsLines(b, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+
+	log.PanicIf(err)
+
+	if len(lines) != 1 || lines[0] != "SUMMARY:"+strings.Repeat("é", 100) {
+		t.Fatalf("Unfolded lines not correct: %v", lines)
+	}
+}
@@ -0,0 +1,49 @@
+package timeindex
+
+import (
+	"github.com/dsoprea/go-logging"
+)
+
+// IntervalJoin calls the callback for every entry of `ts` with the intervals
+// that contain it (including at their end times, as with
+// `TimeIntervalSlice.Search`), in order of their start times. Both slices must
+// be sorted. They are walked together, which takes time proportional to their
+// lengths plus the number of matches.
+func IntervalJoin(ts TimeSlice, tis TimeIntervalSlice, cb func(te TimeEntry, intervals []TimeInterval) error) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	// The intervals that have started, in order of their start times. Those
+	// that have ended are dropped when they are next visited since the
+	// entries only move forward.
+	active := make([]TimeInterval, 0)
+
+	j := 0
+	for _, te := range ts {
+		for ; j < len(tis) && tis[j].From.After(te.Time) == false; j++ {
+			active = append(active, tis[j])
+		}
+
+		matches := make([]TimeInterval, 0)
+
+		kept := active[:0]
+		for _, ti := range active {
+			if ti.To.Before(te.Time) == true {
+				continue
+			}
+
+			kept = append(kept, ti)
+			matches = append(matches, ti)
+		}
+
+		active = kept
+
+		err := cb(te, matches)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
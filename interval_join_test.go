@@ -0,0 +1,111 @@
+package timeindex
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestIntervalJoin(t *testing.T) {
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(getTestMinute(0), getTestMinute(60), "deploy-1")
+	tis = tis.Add(getTestMinute(30), getTestMinute(40), "canary")
+	tis = tis.Add(getTestMinute(60), getTestMinute(120), "deploy-2")
+
+	ts := make(TimeSlice, 0)
+	for _, minutes := range []int{-5, 10, 35, 60, 90, 200} {
+		ts = ts.Add(getTestMinute(minutes), minutes)
+	}
+
+	labels := make([][]string, 0)
+
+	cb := func(te TimeEntry, intervals []TimeInterval) error {
+		names := make([]string, len(intervals))
+		for i, ti := range intervals {
+			names[i] = ti.Items[0].(string)
+		}
+
+		labels = append(labels, names)
+
+		return nil
+	}
+
+	err := IntervalJoin(ts, tis, cb)
+	log.PanicIf(err)
+
+	expected := [][]string{
+		{},
+		{"deploy-1"},
+		{"deploy-1", "canary"},
+		{"deploy-1", "deploy-2"},
+		{"deploy-2"},
+		{},
+	}
+
+	if len(labels) != len(expected) {
+		t.Fatalf("Event count not correct: %v", labels)
+	}
+
+	for i, names := range expected {
+		if len(labels[i]) != len(names) {
+			t.Fatalf("Event (%d) not correct: %v", i, labels[i])
+		}
+
+		for j, name := range names {
+			if labels[i][j] != name {
+				t.Fatalf("Event (%d) not correct: %v", i, labels[i])
+			}
+		}
+	}
+}
+
+func TestIntervalJoin_BruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	tis := make(TimeIntervalSlice, 0)
+	for i := 0; i < 200; i++ {
+		from := getTestSecond(r.Intn(1000))
+		to := from.Add(time.Duration(r.Intn(100)+1) * time.Second)
+
+		tis = tis.Add(from, to, i)
+	}
+
+	ts := make(TimeSlice, 0)
+	for i := 0; i < 300; i++ {
+		ts = ts.Add(getTestSecond(r.Intn(1200)), i)
+	}
+
+	cb := func(te TimeEntry, intervals []TimeInterval) error {
+		expected := make(map[int]bool)
+
+		for _, ti := range tis {
+			if ti.From.After(te.Time) == false && ti.To.Before(te.Time) == false {
+				for _, item := range ti.Items {
+					expected[item.(int)] = true
+				}
+			}
+		}
+
+		actual := 0
+		for _, ti := range intervals {
+			for _, item := range ti.Items {
+				if expected[item.(int)] == false {
+					t.Fatalf("Unexpected interval for [%s]: %v", te.Time, ti)
+				}
+
+				actual++
+			}
+		}
+
+		if actual != len(expected) {
+			t.Fatalf("Match count for [%s] not correct: (%d) != (%d)", te.Time, actual, len(expected))
+		}
+
+		return nil
+	}
+
+	err := IntervalJoin(ts, tis, cb)
+	log.PanicIf(err)
+}
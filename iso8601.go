@@ -0,0 +1,273 @@
+package timeindex
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	isoDurationRe = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+	isoTimeLayouts = []string{
+		"2006-01-02T15:04:05.999999999Z07:00",
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02T15:04",
+		"20060102T150405Z0700",
+		"20060102T150405Z07",
+		"20060102T150405",
+		"2006-01-02",
+		"20060102",
+	}
+)
+
+// IsoDuration is an ISO 8601 duration (e.g. "P1M", "P1DT12H", or "PT0.5S").
+// Years, months, weeks, and days are calendar units whose lengths depend on
+// the time and location that they are applied to.
+type IsoDuration struct {
+	Years  int
+	Months int
+	Weeks  int
+	Days   int
+
+	// Duration is the hours, minutes, and seconds.
+	Duration time.Duration
+}
+
+// ParseIsoDuration parses an ISO 8601 duration. Only the seconds may have a
+// fraction.
+func ParseIsoDuration(s string) (d IsoDuration, err error) {
+	upper := strings.ToUpper(s)
+
+	match := isoDurationRe.FindStringSubmatch(upper)
+	if match == nil || upper == "P" || strings.HasSuffix(upper, "T") == true {
+		return d, fmt.Errorf("ISO 8601 duration not valid: [%s]", s)
+	}
+
+	components := make([]int, 6)
+	for i := range components {
+		if match[i+1] == "" {
+			continue
+		}
+
+		if components[i], err = strconv.Atoi(match[i+1]); err != nil {
+			return d, fmt.Errorf("ISO 8601 duration not valid: [%s]", s)
+		}
+	}
+
+	d.Years = components[0]
+	d.Months = components[1]
+	d.Weeks = components[2]
+	d.Days = components[3]
+
+	// The seconds may have a fraction of up to nanosecond precision (finer
+	// digits are dropped).
+	seconds, nanoseconds := int64(0), int64(0)
+	if match[7] != "" {
+		parts := strings.SplitN(strings.Replace(match[7], ",", ".", 1), ".", 2)
+		if seconds, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+			return d, fmt.Errorf("ISO 8601 duration not valid: [%s]", s)
+		}
+
+		if len(parts) == 2 {
+			fraction := (parts[1] + "000000000")[:9]
+			if nanoseconds, err = strconv.ParseInt(fraction, 10, 64); err != nil {
+				return d, fmt.Errorf("ISO 8601 duration not valid: [%s]", s)
+			}
+		}
+	}
+
+	// Add up the hours, minutes, and seconds without overflowing a
+	// `time.Duration`.
+	total := int64(0)
+	for _, part := range []struct {
+		value int64
+		unit  time.Duration
+	}{
+		{int64(components[4]), time.Hour},
+		{int64(components[5]), time.Minute},
+		{seconds, time.Second},
+		{nanoseconds, time.Nanosecond},
+	} {
+		if part.value > (math.MaxInt64-total)/int64(part.unit) {
+			return d, fmt.Errorf("ISO 8601 duration is too long: [%s]", s)
+		}
+
+		total += part.value * int64(part.unit)
+	}
+
+	d.Duration = time.Duration(total)
+
+	return d, nil
+}
+
+// String returns the ISO 8601 form of the duration.
+func (d IsoDuration) String() string {
+	b := new(bytes.Buffer)
+	b.WriteString("P")
+
+	for _, part := range []struct {
+		value      int
+		designator string
+	}{
+		{d.Years, "Y"},
+		{d.Months, "M"},
+		{d.Weeks, "W"},
+		{d.Days, "D"},
+	} {
+		if part.value != 0 {
+			fmt.Fprintf(b, "%d%s", part.value, part.designator)
+		}
+	}
+
+	if d.Duration != 0 || b.Len() == 1 {
+		b.WriteString("T")
+
+		hours := d.Duration / time.Hour
+		minutes := (d.Duration % time.Hour) / time.Minute
+		seconds := (d.Duration % time.Minute) / time.Second
+		nanoseconds := d.Duration % time.Second
+
+		if hours != 0 {
+			fmt.Fprintf(b, "%dH", hours)
+		}
+
+		if minutes != 0 {
+			fmt.Fprintf(b, "%dM", minutes)
+		}
+
+		if nanoseconds != 0 {
+			fraction := strings.TrimRight(fmt.Sprintf("%09d", nanoseconds), "0")
+			fmt.Fprintf(b, "%d.%sS", seconds, fraction)
+		} else if seconds != 0 || d.Duration == 0 {
+			fmt.Fprintf(b, "%dS", seconds)
+		}
+	}
+
+	return b.String()
+}
+
+// AddTo returns `t` plus the duration. The calendar units are applied in the
+// given location.
+func (d IsoDuration) AddTo(t time.Time, location *time.Location) time.Time {
+	return t.In(location).AddDate(d.Years, d.Months, d.Weeks*7+d.Days).Add(d.Duration)
+}
+
+// SubtractFrom returns `t` minus the duration. The calendar units are applied
+// in the given location.
+func (d IsoDuration) SubtractFrom(t time.Time, location *time.Location) time.Time {
+	return t.In(location).Add(-d.Duration).AddDate(-d.Years, -d.Months, -(d.Weeks*7 + d.Days))
+}
+
+// parseIsoTime parses an ISO 8601 date or date-time. Times without a zone are
+// in the given location.
+func parseIsoTime(s string, location *time.Location) (t time.Time, err error) {
+	for _, layout := range isoTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, location); err == nil {
+			return t, nil
+		}
+	}
+
+	return t, fmt.Errorf("ISO 8601 time not valid: [%s]", s)
+}
+
+// ParseTimeInterval parses an ISO 8601 interval in any of the "start/end",
+// "start/duration", or "duration/end" forms (e.g.
+// "2024-03-01T00:00Z/2024-03-02T00:00Z", "2024-03-01T00:00Z/P1D", or
+// "P1M/2024-04-01"). Times without a zone, and the calendar units of
+// durations, are in `location` (which defaults to UTC). The interval must not
+// be empty.
+func ParseTimeInterval(s string, location *time.Location) (ti TimeInterval, err error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) != 2 {
+		return ti, fmt.Errorf("ISO 8601 interval not valid: [%s]", s)
+	}
+
+	isDuration := func(part string) bool {
+		return strings.HasPrefix(strings.ToUpper(part), "P")
+	}
+
+	var from, to time.Time
+
+	if isDuration(parts[0]) == true && isDuration(parts[1]) == true {
+		return ti, fmt.Errorf("ISO 8601 interval needs at least one time: [%s]", s)
+	} else if isDuration(parts[1]) == true {
+		if from, err = parseIsoTime(parts[0], location); err != nil {
+			return ti, err
+		}
+
+		d, err := ParseIsoDuration(parts[1])
+		if err != nil {
+			return ti, err
+		}
+
+		to = d.AddTo(from, location)
+	} else if isDuration(parts[0]) == true {
+		if to, err = parseIsoTime(parts[1], location); err != nil {
+			return ti, err
+		}
+
+		d, err := ParseIsoDuration(parts[0])
+		if err != nil {
+			return ti, err
+		}
+
+		from = d.SubtractFrom(to, location)
+	} else {
+		if from, err = parseIsoTime(parts[0], location); err != nil {
+			return ti, err
+		} else if to, err = parseIsoTime(parts[1], location); err != nil {
+			return ti, err
+		}
+	}
+
+	if from.Before(to) == false {
+		return ti, fmt.Errorf("ISO 8601 interval is empty: [%s]", s)
+	}
+
+	ti = TimeInterval{
+		From:  from,
+		To:    to,
+		Items: []interface{}{},
+	}
+
+	return ti, nil
+}
+
+// String returns the interval in the ISO 8601 "start/end" form. The items are
+// not included.
+func (ti TimeInterval) String() string {
+	return ti.From.Format(time.RFC3339Nano) + "/" + ti.To.Format(time.RFC3339Nano)
+}
+
+// MarshalText returns the interval in the ISO 8601 "start/end" form. The items
+// are not included.
+func (ti TimeInterval) MarshalText() (text []byte, err error) {
+	if ti.From.Before(ti.To) == false {
+		return nil, fmt.Errorf("interval is invalid: [%s] - [%s]", ti.From, ti.To)
+	}
+
+	return []byte(ti.String()), nil
+}
+
+// UnmarshalText parses any of the forms that `ParseTimeInterval` accepts (in
+// UTC). The items are cleared.
+func (ti *TimeInterval) UnmarshalText(text []byte) (err error) {
+	parsed, err := ParseTimeInterval(string(text), time.UTC)
+	if err != nil {
+		return err
+	}
+
+	*ti = parsed
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package timeindex
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestParseIsoDuration(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected IsoDuration
+		text     string
+	}{
+		{"P1D", IsoDuration{Days: 1}, "P1D"},
+		{"P1Y2M3DT4H5M6S", IsoDuration{Years: 1, Months: 2, Days: 3, Duration: time.Hour*4 + time.Minute*5 + time.Second*6}, "P1Y2M3DT4H5M6S"},
+		{"P2W", IsoDuration{Weeks: 2}, "P2W"},
+		{"PT0.5S", IsoDuration{Duration: time.Millisecond * 500}, "PT0.5S"},
+		{"PT1,25S", IsoDuration{Duration: time.Millisecond * 1250}, "PT1.25S"},
+		{"pt90m", IsoDuration{Duration: time.Minute * 90}, "PT1H30M"},
+		{"PT0S", IsoDuration{}, "PT0S"},
+	}
+
+	for _, c := range cases {
+		d, err := ParseIsoDuration(c.s)
+		log.PanicIf(err)
+
+		if d != c.expected {
+			t.Fatalf("Duration for [%s] not correct: %#v", c.s, d)
+		} else if d.String() != c.text {
+			t.Fatalf("Text for [%s] not correct: [%s]", c.s, d.String())
+		}
+	}
+}
+
+func TestParseIsoDuration_RoundTrip(t *testing.T) {
+	cases := []IsoDuration{
+		{Duration: time.Second*133 + time.Nanosecond},
+		{Duration: time.Nanosecond},
+		{Duration: time.Millisecond*999 + time.Nanosecond*999},
+		{Days: 1, Duration: time.Hour*25 + time.Second*59 + time.Nanosecond*123456789},
+		{Duration: time.Duration(math.MaxInt64)},
+	}
+
+	for _, expected := range cases {
+		d, err := ParseIsoDuration(expected.String())
+		log.PanicIf(err)
+
+		if d != expected {
+			t.Fatalf("Duration for [%s] not correct: %#v", expected.String(), d)
+		}
+	}
+
+	// Digits finer than a nanosecond are dropped.
+	d, err := ParseIsoDuration("PT133.0000000019S")
+	log.PanicIf(err)
+
+	if d.Duration != time.Second*133+time.Nanosecond {
+		t.Fatalf("Duration not correct: [%s]", d.Duration)
+	}
+}
+
+func TestParseIsoDuration_Invalid(t *testing.T) {
+	for _, s := range []string{"", "P", "p", "PT", "pt", "1D", "P1DT", "p1dt", "P1.5D", "PT1H2S3M", "P-1D", "P99999999999999999999D", "PT9999999H", "PT99999999999999M", "PT9999999999999S", "PT2562047H47M16.854775808S"} {
+		if _, err := ParseIsoDuration(s); err == nil {
+			t.Fatalf("Expected error for [%s].", s)
+		}
+	}
+}
+
+func TestParseTimeInterval_Overflow(t *testing.T) {
+	for _, s := range []string{"2024-01-01T00:00Z/P99999999999999999999D", "2024-01-01T00:00Z/PT99999999999999999999H"} {
+		if _, err := ParseTimeInterval(s, nil); err == nil {
+			t.Fatalf("Expected error for [%s].", s)
+		}
+	}
+}
+
+func TestIsoDuration_AddTo(t *testing.T) {
+	location := getNewYork()
+
+	// The day before the spring-forward change.
+	from := time.Date(2016, 3, 12, 12, 0, 0, 0, location)
+
+	d := IsoDuration{Days: 1}
+	if to := d.AddTo(from, location); to.Sub(from) != time.Hour*23 {
+		t.Fatalf("Calendar day not correct: [%s]", to)
+	}
+
+	d = IsoDuration{Duration: time.Hour * 24}
+	if to := d.AddTo(from, location); to.Sub(from) != time.Hour*24 {
+		t.Fatalf("Exact duration not correct: [%s]", to)
+	}
+
+	d = IsoDuration{Months: 1}
+	if before := d.SubtractFrom(from, location); before.Equal(time.Date(2016, 2, 12, 12, 0, 0, 0, location)) == false {
+		t.Fatalf("Subtracted month not correct: [%s]", before)
+	}
+}
+
+func TestParseTimeInterval(t *testing.T) {
+	location := getNewYork()
+
+	utc := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	local := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, location)
+	}
+
+	cases := []struct {
+		s        string
+		from, to time.Time
+	}{
+		{"2024-03-01T00:00Z/P1D", utc(2024, 3, 1, 0), utc(2024, 3, 2, 0)},
+		{"2024-03-01T00:00:00Z/2024-03-01T12:00:00Z", utc(2024, 3, 1, 0), utc(2024, 3, 1, 12)},
+		{"2024-03-01T00:00:00-05:00/PT1H", local(2024, 3, 1, 0), local(2024, 3, 1, 1)},
+		{"20240301T000000Z/20240302T000000Z", utc(2024, 3, 1, 0), utc(2024, 3, 2, 0)},
+		{"P1M/2024-04-01", local(2024, 3, 1, 0), local(2024, 4, 1, 0)},
+		{"2024-01-31/P1M", local(2024, 1, 31, 0), local(2024, 3, 2, 0)},
+		{"2024-03-09T12:00/P1D", local(2024, 3, 9, 12), local(2024, 3, 10, 12)},
+		{"2023-06-01/P1Y", local(2023, 6, 1, 0), local(2024, 6, 1, 0)},
+		{"PT1H30M/2024-03-01T12:00Z", utc(2024, 3, 1, 10).Add(time.Minute * 30), utc(2024, 3, 1, 12)},
+	}
+
+	for _, c := range cases {
+		ti, err := ParseTimeInterval(c.s, location)
+		log.PanicIf(err)
+
+		if ti.From.Equal(c.from) == false || ti.To.Equal(c.to) == false {
+			t.Fatalf("Interval for [%s] not correct: [%s] - [%s]", c.s, ti.From, ti.To)
+		}
+	}
+
+	// The day of the spring-forward change only has 23 hours.
+	ti, err := ParseTimeInterval("2024-03-10/P1D", location)
+	log.PanicIf(err)
+
+	if ti.To.Sub(ti.From) != time.Hour*23 {
+		t.Fatalf("Calendar day not correct: [%s] - [%s]", ti.From, ti.To)
+	}
+}
+
+func TestParseTimeInterval_DefaultLocation(t *testing.T) {
+	ti, err := ParseTimeInterval("2024-03-01/P1D", nil)
+	log.PanicIf(err)
+
+	if ti.From.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) == false {
+		t.Fatalf("Interval not correct: [%s] - [%s]", ti.From, ti.To)
+	}
+}
+
+func TestParseTimeInterval_Invalid(t *testing.T) {
+	for _, s := range []string{"", "2024-03-01", "P1D/P1D", "2024-03-01/", "2024-03-02/2024-03-01", "2024-03-01/PT0S", "2024-03-01/P1X", "a/b", "2024-03-01/P1D/P1D"} {
+		if _, err := ParseTimeInterval(s, nil); err == nil {
+			t.Fatalf("Expected error for [%s].", s)
+		}
+	}
+}
+
+func TestTimeInterval_MarshalText(t *testing.T) {
+	ti := TimeInterval{
+		From:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:    time.Date(2024, 3, 1, 12, 30, 0, 500000000, time.UTC),
+		Items: []interface{}{"ignored"},
+	}
+
+	expected := "2024-03-01T00:00:00Z/2024-03-01T12:30:00.5Z"
+	if ti.String() != expected {
+		t.Fatalf("String not correct: [%s]", ti.String())
+	}
+
+	text, err := ti.MarshalText()
+	log.PanicIf(err)
+
+	if string(text) != expected {
+		t.Fatalf("Text not correct: [%s]", string(text))
+	}
+
+	var recovered TimeInterval
+
+	err = recovered.UnmarshalText(text)
+	log.PanicIf(err)
+
+	if recovered.From.Equal(ti.From) == false || recovered.To.Equal(ti.To) == false || len(recovered.Items) != 0 {
+		t.Fatalf("Recovered interval not correct: [%s]", recovered)
+	}
+
+	err = recovered.UnmarshalText([]byte("2024-03-01T00:00Z/P1D"))
+	log.PanicIf(err)
+
+	if recovered.To.Equal(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)) == false {
+		t.Fatalf("Recovered interval not correct: [%s]", recovered)
+	}
+
+	if _, err := (TimeInterval{}).MarshalText(); err == nil {
+		t.Fatalf("Expected error for empty interval.")
+	}
+}
@@ -0,0 +1,358 @@
+package timeindex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	ErrUnsorted = errors.New("not sorted")
+)
+
+// JsonItemCodec converts the items stored alongside the times to and from
+// JSON.
+type JsonItemCodec interface {
+	EncodeItem(item interface{}) (raw json.RawMessage, err error)
+	DecodeItem(raw json.RawMessage) (item interface{}, err error)
+}
+
+// GenericJsonItemCodec encodes items using the standard JSON encoder and
+// decodes them into the generic types (map[string]interface{}, []interface{},
+// float64, string, bool, nil).
+type GenericJsonItemCodec struct{}
+
+func (GenericJsonItemCodec) EncodeItem(item interface{}) (raw json.RawMessage, err error) {
+	return json.Marshal(item)
+}
+
+func (GenericJsonItemCodec) DecodeItem(raw json.RawMessage) (item interface{}, err error) {
+	err = json.Unmarshal(raw, &item)
+	return item, err
+}
+
+var (
+	// DefaultJsonItemCodec is the codec used by the `json.Marshaler` and
+	// `json.Unmarshaler` implementations.
+	DefaultJsonItemCodec JsonItemCodec = GenericJsonItemCodec{}
+)
+
+type jsonTimeEntry struct {
+	Time  string            `json:"time"`
+	Items []json.RawMessage `json:"items"`
+}
+
+type jsonTimeInterval struct {
+	From  string            `json:"from"`
+	To    string            `json:"to"`
+	Items []json.RawMessage `json:"items"`
+}
+
+func encodeJsonItems(items []interface{}, codec JsonItemCodec) (encoded []json.RawMessage, err error) {
+	encoded = make([]json.RawMessage, len(items))
+	for i, item := range items {
+		encoded[i], err = codec.EncodeItem(item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return encoded, nil
+}
+
+func decodeJsonItems(encoded []json.RawMessage, codec JsonItemCodec) (items []interface{}, err error) {
+	items = make([]interface{}, len(encoded))
+	for i, raw := range encoded {
+		items[i], err = codec.DecodeItem(raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+func parseJsonTime(s string) (t time.Time, err error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func (te TimeEntry) toJson(codec JsonItemCodec) (jte jsonTimeEntry, err error) {
+	items, err := encodeJsonItems(te.Items, codec)
+	if err != nil {
+		return jte, err
+	}
+
+	jte = jsonTimeEntry{
+		Time:  te.Time.Format(time.RFC3339Nano),
+		Items: items,
+	}
+
+	return jte, nil
+}
+
+func (jte jsonTimeEntry) fromJson(codec JsonItemCodec) (te TimeEntry, err error) {
+	t, err := parseJsonTime(jte.Time)
+	if err != nil {
+		return te, err
+	}
+
+	items, err := decodeJsonItems(jte.Items, codec)
+	if err != nil {
+		return te, err
+	}
+
+	te = TimeEntry{
+		Time:  t,
+		Items: items,
+	}
+
+	return te, nil
+}
+
+func (ti TimeInterval) toJson(codec JsonItemCodec) (jti jsonTimeInterval, err error) {
+	items, err := encodeJsonItems(ti.Items, codec)
+	if err != nil {
+		return jti, err
+	}
+
+	jti = jsonTimeInterval{
+		From:  ti.From.Format(time.RFC3339Nano),
+		To:    ti.To.Format(time.RFC3339Nano),
+		Items: items,
+	}
+
+	return jti, nil
+}
+
+func (jti jsonTimeInterval) fromJson(codec JsonItemCodec) (ti TimeInterval, err error) {
+	from, err := parseJsonTime(jti.From)
+	if err != nil {
+		return ti, err
+	}
+
+	to, err := parseJsonTime(jti.To)
+	if err != nil {
+		return ti, err
+	}
+
+	if from.Before(to) == false {
+		return ti, fmt.Errorf("interval is invalid: [%s] - [%s]", jti.From, jti.To)
+	}
+
+	items, err := decodeJsonItems(jti.Items, codec)
+	if err != nil {
+		return ti, err
+	}
+
+	ti = TimeInterval{
+		From:  from,
+		To:    to,
+		Items: items,
+	}
+
+	return ti, nil
+}
+
+// MarshalJSON encodes the entry using the default item codec.
+func (te TimeEntry) MarshalJSON() (data []byte, err error) {
+	jte, err := te.toJson(DefaultJsonItemCodec)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jte)
+}
+
+// UnmarshalJSON decodes the entry using the default item codec.
+func (te *TimeEntry) UnmarshalJSON(data []byte) (err error) {
+	jte := jsonTimeEntry{}
+	if err := json.Unmarshal(data, &jte); err != nil {
+		return err
+	}
+
+	*te, err = jte.fromJson(DefaultJsonItemCodec)
+	return err
+}
+
+// MarshalJSON encodes the interval using the default item codec.
+func (ti TimeInterval) MarshalJSON() (data []byte, err error) {
+	jti, err := ti.toJson(DefaultJsonItemCodec)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jti)
+}
+
+// UnmarshalJSON decodes the interval using the default item codec.
+func (ti *TimeInterval) UnmarshalJSON(data []byte) (err error) {
+	jti := jsonTimeInterval{}
+	if err := json.Unmarshal(data, &jti); err != nil {
+		return err
+	}
+
+	*ti, err = jti.fromJson(DefaultJsonItemCodec)
+	return err
+}
+
+// EncodeTimeSliceJson encodes the slice using the given item codec.
+func EncodeTimeSliceJson(ts TimeSlice, codec JsonItemCodec) (data []byte, err error) {
+	encoded := make([]jsonTimeEntry, len(ts))
+	for i, te := range ts {
+		encoded[i], err = te.toJson(codec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(encoded)
+}
+
+// DecodeTimeSliceJson decodes a slice using the given item codec. If `strict`
+// is true, input that is not sorted or that has repeated times is rejected
+// with `ErrUnsorted`. Otherwise, it is sorted and the items of repeated times
+// are combined.
+func DecodeTimeSliceJson(data []byte, codec JsonItemCodec, strict bool) (ts TimeSlice, err error) {
+	encoded := make([]jsonTimeEntry, 0)
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+
+	ts = make(TimeSlice, len(encoded))
+	for i, jte := range encoded {
+		ts[i], err = jte.fromJson(codec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	isSorted := true
+	for i := 1; i < len(ts); i++ {
+		if ts[i-1].Time.Before(ts[i].Time) == false {
+			isSorted = false
+			break
+		}
+	}
+
+	if isSorted == true {
+		return ts, nil
+	} else if strict == true {
+		return nil, ErrUnsorted
+	}
+
+	return sortAndCombineTimeSlice(ts), nil
+}
+
+// sortAndCombineTimeSlice sorts the entries (keeping the order of equal times)
+// and combines the items of repeated times.
+func sortAndCombineTimeSlice(ts TimeSlice) TimeSlice {
+	sort.Stable(ts)
+
+	merged := make(TimeSlice, 0, len(ts))
+	for _, te := range ts {
+		last := len(merged) - 1
+		if last >= 0 && merged[last].Time.Equal(te.Time) {
+			merged[last].Items = append(merged[last].Items, te.Items...)
+			continue
+		}
+
+		merged = append(merged, te)
+	}
+
+	return merged
+}
+
+// MarshalJSON encodes the slice using the default item codec.
+func (ts TimeSlice) MarshalJSON() (data []byte, err error) {
+	return EncodeTimeSliceJson(ts, DefaultJsonItemCodec)
+}
+
+// UnmarshalJSON decodes the slice using the default item codec. Unsorted
+// input is sorted.
+func (ts *TimeSlice) UnmarshalJSON(data []byte) (err error) {
+	*ts, err = DecodeTimeSliceJson(data, DefaultJsonItemCodec, false)
+	return err
+}
+
+// EncodeTimeIntervalSliceJson encodes the slice using the given item codec.
+func EncodeTimeIntervalSliceJson(tis TimeIntervalSlice, codec JsonItemCodec) (data []byte, err error) {
+	encoded := make([]jsonTimeInterval, len(tis))
+	for i, ti := range tis {
+		encoded[i], err = ti.toJson(codec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(encoded)
+}
+
+// DecodeTimeIntervalSliceJson decodes a slice using the given item codec. If
+// `strict` is true, input that is not sorted by (from, to) or that has
+// repeated intervals is rejected with `ErrUnsorted`. Otherwise, it is sorted
+// and the items of repeated intervals are combined.
+func DecodeTimeIntervalSliceJson(data []byte, codec JsonItemCodec, strict bool) (tis TimeIntervalSlice, err error) {
+	encoded := make([]jsonTimeInterval, 0)
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+
+	tis = make(TimeIntervalSlice, len(encoded))
+	for i, jti := range encoded {
+		tis[i], err = jti.fromJson(codec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	isSorted := true
+	for i := 1; i < len(tis); i++ {
+		if compareIntervals(tis[i-1], tis[i]) >= 0 {
+			isSorted = false
+			break
+		}
+	}
+
+	if isSorted == true {
+		return tis, nil
+	} else if strict == true {
+		return nil, ErrUnsorted
+	}
+
+	return sortAndCombineTimeIntervalSlice(tis), nil
+}
+
+// sortAndCombineTimeIntervalSlice sorts the intervals by (from, to) (keeping
+// the order of equal intervals) and combines the items of repeated intervals.
+func sortAndCombineTimeIntervalSlice(tis TimeIntervalSlice) TimeIntervalSlice {
+	sort.SliceStable(tis, func(i, j int) bool {
+		return compareIntervals(tis[i], tis[j]) < 0
+	})
+
+	merged := make(TimeIntervalSlice, 0, len(tis))
+	for _, ti := range tis {
+		last := len(merged) - 1
+		if last >= 0 && compareIntervals(merged[last], ti) == 0 {
+			merged[last].Items = append(merged[last].Items, ti.Items...)
+			continue
+		}
+
+		merged = append(merged, ti)
+	}
+
+	return merged
+}
+
+// MarshalJSON encodes the slice using the default item codec.
+func (tis TimeIntervalSlice) MarshalJSON() (data []byte, err error) {
+	return EncodeTimeIntervalSliceJson(tis, DefaultJsonItemCodec)
+}
+
+// UnmarshalJSON decodes the slice using the default item codec. Unsorted
+// input is sorted.
+func (tis *TimeIntervalSlice) UnmarshalJSON(data []byte) (err error) {
+	*tis, err = DecodeTimeIntervalSliceJson(data, DefaultJsonItemCodec, false)
+	return err
+}
@@ -0,0 +1,205 @@
+package timeindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestTimeSlice_JsonRoundTrip(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339Nano, "2016-12-02T08:05:44.123456789Z")
+	log.PanicIf(err)
+
+	time2, err := time.Parse(time.RFC3339, "2016-12-02T09:05:44Z")
+	log.PanicIf(err)
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(time2, "b")
+	ts = ts.Add(time1, "a")
+	ts = ts.Add(time1, 3.0)
+	ts = ts.Add(time2, nil)
+
+	data, err := json.Marshal(ts)
+	log.PanicIf(err)
+
+	expected := `[{"time":"2016-12-02T08:05:44.123456789Z","items":["a",3]},{"time":"2016-12-02T09:05:44Z","items":["b"]}]`
+	if string(data) != expected {
+		t.Fatalf("Encoding not correct: [%s]", string(data))
+	}
+
+	var recovered TimeSlice
+
+	err = json.Unmarshal(data, &recovered)
+	log.PanicIf(err)
+
+	if len(recovered) != 2 {
+		t.Fatalf("Entry count not correct: (%d)", len(recovered))
+	} else if recovered[0].Time.Equal(time1) == false || recovered[1].Time.Equal(time2) == false {
+		t.Fatalf("Times not correct: %v", recovered)
+	} else if reflect.DeepEqual(recovered[0].Items, []interface{}{"a", 3.0}) == false {
+		t.Fatalf("Items not correct: %v", recovered[0].Items)
+	} else if reflect.DeepEqual(recovered[1].Items, []interface{}{"b"}) == false {
+		t.Fatalf("Items not correct: %v", recovered[1].Items)
+	}
+}
+
+func TestTimeSlice_UnmarshalJSON_Unsorted(t *testing.T) {
+	data := []byte(`[
+		{"time":"2016-12-02T10:00:00Z","items":["c"]},
+		{"time":"2016-12-02T08:00:00Z","items":["a"]},
+		{"time":"2016-12-02T10:00:00Z","items":["d"]},
+		{"time":"2016-12-02T09:00:00Z","items":[]}
+	]`)
+
+	var ts TimeSlice
+
+	err := json.Unmarshal(data, &ts)
+	log.PanicIf(err)
+
+	if len(ts) != 3 {
+		t.Fatalf("Entry count not correct: (%d)", len(ts))
+	}
+
+	for i, hour := range []int{8, 9, 10} {
+		if ts[i].Time.Hour() != hour {
+			t.Fatalf("Entry (%d) not sorted: %v", i, ts)
+		}
+	}
+
+	if reflect.DeepEqual(ts[2].Items, []interface{}{"c", "d"}) == false {
+		t.Fatalf("Items of repeated time not combined: %v", ts[2].Items)
+	}
+
+	_, err = DecodeTimeSliceJson(data, DefaultJsonItemCodec, true)
+	if err != ErrUnsorted {
+		t.Fatalf("Expected unsorted error: %v", err)
+	}
+}
+
+func TestTimeSlice_UnmarshalJSON_BadTime(t *testing.T) {
+	var ts TimeSlice
+
+	err := json.Unmarshal([]byte(`[{"time":"yesterday","items":[]}]`), &ts)
+	if err == nil {
+		t.Fatalf("Expected error for invalid time.")
+	}
+}
+
+type testIntJsonItemCodec struct{}
+
+func (testIntJsonItemCodec) EncodeItem(item interface{}) (raw json.RawMessage, err error) {
+	return json.RawMessage(strconv.Quote(fmt.Sprintf("%d", item.(int)))), nil
+}
+
+func (testIntJsonItemCodec) DecodeItem(raw json.RawMessage) (item interface{}, err error) {
+	s, err := strconv.Unquote(string(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	return strconv.Atoi(s)
+}
+
+func TestEncodeTimeSliceJson_Codec(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339, "2016-12-02T08:05:44Z")
+	log.PanicIf(err)
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(time1, 11)
+	ts = ts.Add(time1, 22)
+
+	codec := testIntJsonItemCodec{}
+
+	data, err := EncodeTimeSliceJson(ts, codec)
+	log.PanicIf(err)
+
+	if string(data) != `[{"time":"2016-12-02T08:05:44Z","items":["11","22"]}]` {
+		t.Fatalf("Encoding not correct: [%s]", string(data))
+	}
+
+	recovered, err := DecodeTimeSliceJson(data, codec, true)
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(recovered[0].Items, []interface{}{11, 22}) == false {
+		t.Fatalf("Items not correct: %v", recovered[0].Items)
+	}
+}
+
+func TestTimeIntervalSlice_JsonRoundTrip(t *testing.T) {
+	left1, err := time.Parse(time.RFC3339, "2016-12-03T07:23:50Z")
+	log.PanicIf(err)
+
+	right1, err := time.Parse(time.RFC3339, "2016-12-04T07:23:50Z")
+	log.PanicIf(err)
+
+	right2, err := time.Parse(time.RFC3339, "2016-12-05T07:23:50Z")
+	log.PanicIf(err)
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(left1, right2, "b")
+	tis = tis.Add(left1, right1, "a")
+
+	data, err := json.Marshal(tis)
+	log.PanicIf(err)
+
+	expected := `[{"from":"2016-12-03T07:23:50Z","to":"2016-12-04T07:23:50Z","items":["a"]},{"from":"2016-12-03T07:23:50Z","to":"2016-12-05T07:23:50Z","items":["b"]}]`
+	if string(data) != expected {
+		t.Fatalf("Encoding not correct: [%s]", string(data))
+	}
+
+	var recovered TimeIntervalSlice
+
+	err = json.Unmarshal(data, &recovered)
+	log.PanicIf(err)
+
+	if len(recovered) != 2 {
+		t.Fatalf("Interval count not correct: (%d)", len(recovered))
+	} else if recovered[0].To.Equal(right1) == false || recovered[1].To.Equal(right2) == false {
+		t.Fatalf("Intervals not correct: %v", recovered)
+	} else if reflect.DeepEqual(recovered[1].Items, []interface{}{"b"}) == false {
+		t.Fatalf("Items not correct: %v", recovered[1].Items)
+	}
+}
+
+func TestTimeIntervalSlice_UnmarshalJSON_Unsorted(t *testing.T) {
+	data := []byte(`[
+		{"from":"2016-12-03T00:00:00Z","to":"2016-12-05T00:00:00Z","items":["c"]},
+		{"from":"2016-12-03T00:00:00Z","to":"2016-12-04T00:00:00Z","items":["b"]},
+		{"from":"2016-12-01T00:00:00Z","to":"2016-12-09T00:00:00Z","items":["a"]},
+		{"from":"2016-12-03T00:00:00Z","to":"2016-12-04T00:00:00Z","items":["d"]}
+	]`)
+
+	var tis TimeIntervalSlice
+
+	err := json.Unmarshal(data, &tis)
+	log.PanicIf(err)
+
+	if len(tis) != 3 {
+		t.Fatalf("Interval count not correct: (%d)", len(tis))
+	} else if reflect.DeepEqual(tis[0].Items, []interface{}{"a"}) == false {
+		t.Fatalf("First interval not correct: %v", tis[0])
+	} else if reflect.DeepEqual(tis[1].Items, []interface{}{"b", "d"}) == false {
+		t.Fatalf("Second interval not correct: %v", tis[1])
+	} else if reflect.DeepEqual(tis[2].Items, []interface{}{"c"}) == false {
+		t.Fatalf("Third interval not correct: %v", tis[2])
+	}
+
+	_, err = DecodeTimeIntervalSliceJson(data, DefaultJsonItemCodec, true)
+	if err != ErrUnsorted {
+		t.Fatalf("Expected unsorted error: %v", err)
+	}
+}
+
+func TestTimeInterval_UnmarshalJSON_Invalid(t *testing.T) {
+	var ti TimeInterval
+
+	err := json.Unmarshal([]byte(`{"from":"2016-12-04T00:00:00Z","to":"2016-12-03T00:00:00Z","items":[]}`), &ti)
+	if err == nil {
+		t.Fatalf("Expected error for inverted interval.")
+	}
+}
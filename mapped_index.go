@@ -0,0 +1,330 @@
+package timeindex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// A mapped-index file is a read-only, sorted index that is searched without
+// loading it into memory. Layout (little-endian):
+//
+//   magic (4 bytes) | version (1 byte) | reserved (3 bytes) | count (uint64) |
+//   count x time (int64 nanoseconds since the epoch) |
+//   (count + 1) x payload offset (uint64) |
+//   payloads
+//
+// The payload of each entry is its encoded items. The offsets are relative to
+// the start of the payloads and the last one is the size of the payloads.
+
+const (
+	mappedIndexVersion    = 1
+	mappedIndexHeaderSize = 16
+)
+
+var (
+	mappedIndexMagic = []byte{'T', 'I', 'X', 'M'}
+)
+
+// WriteMappedIndex writes the slice as a mapped-index file. The times must be
+// unique and sorted.
+func WriteMappedIndex(w io.Writer, ts TimeSlice, codec JsonItemCodec) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	count := len(ts)
+
+	keys := make([]byte, count*8)
+	offsets := make([]byte, (count+1)*8)
+	payloads := new(bytes.Buffer)
+
+	previous := int64(0)
+	for i, te := range ts {
+		n, err := toUnixNano(te.Time)
+		log.PanicIf(err)
+
+		if i > 0 && n <= previous {
+			log.Panic(ErrUnsorted)
+		}
+
+		previous = n
+
+		binary.LittleEndian.PutUint64(keys[i*8:], uint64(n))
+		binary.LittleEndian.PutUint64(offsets[i*8:], uint64(payloads.Len()))
+
+		encodedItems, err := encodeStoreItems(te.Items, codec)
+		log.PanicIf(err)
+
+		payloads.Write(encodedItems)
+	}
+
+	binary.LittleEndian.PutUint64(offsets[count*8:], uint64(payloads.Len()))
+
+	header := make([]byte, mappedIndexHeaderSize)
+	copy(header, mappedIndexMagic)
+	header[4] = mappedIndexVersion
+	binary.LittleEndian.PutUint64(header[8:], uint64(count))
+
+	for _, part := range [][]byte{header, keys, offsets, payloads.Bytes()} {
+		_, err := w.Write(part)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// WriteMappedIndexFile writes the slice as a mapped-index file at the given
+// path.
+func WriteMappedIndexFile(filepath string, ts TimeSlice, codec JsonItemCodec) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	f, err := os.Create(filepath)
+	log.PanicIf(err)
+
+	defer f.Close()
+
+	err = WriteMappedIndex(f, ts, codec)
+	log.PanicIf(err)
+
+	err = f.Sync()
+	log.PanicIf(err)
+
+	return nil
+}
+
+// MappedIndex is a read-only index backed by a memory-mapped file. Searches
+// read the mapping directly.
+type MappedIndex struct {
+	codec JsonItemCodec
+
+	data  []byte
+	unmap func() error
+
+	count    int
+	keys     []byte
+	offsets  []byte
+	payloads []byte
+}
+
+// OpenMappedIndex maps a file written by `WriteMappedIndex`.
+func OpenMappedIndex(filepath string, codec JsonItemCodec) (mi *MappedIndex, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	f, err := os.Open(filepath)
+	log.PanicIf(err)
+
+	defer f.Close()
+
+	fi, err := f.Stat()
+	log.PanicIf(err)
+
+	size := fi.Size()
+	if size < mappedIndexHeaderSize {
+		log.Panic(io.ErrUnexpectedEOF)
+	} else if int64(int(size)) != size {
+		log.Panicf("mapped-index file too large for this platform")
+	}
+
+	data, unmap, err := mapFile(f, int(size))
+	log.PanicIf(err)
+
+	mi, err = newMappedIndex(data, codec)
+	if err != nil {
+		unmap()
+		log.Panic(err)
+	}
+
+	mi.unmap = unmap
+
+	return mi, nil
+}
+
+func newMappedIndex(data []byte, codec JsonItemCodec) (mi *MappedIndex, err error) {
+	if bytes.Equal(data[:4], mappedIndexMagic) == false {
+		return nil, fmt.Errorf("mapped-index file not recognized")
+	} else if data[4] != mappedIndexVersion {
+		return nil, fmt.Errorf("mapped-index file version (%d) not supported", data[4])
+	}
+
+	rawCount := binary.LittleEndian.Uint64(data[8:])
+	if rawCount > uint64(len(data)-mappedIndexHeaderSize)/16 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	count := int(rawCount)
+
+	keysEnd := mappedIndexHeaderSize + count*8
+	offsetsEnd := keysEnd + (count+1)*8
+	if offsetsEnd > len(data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	mi = &MappedIndex{
+		codec:    codec,
+		data:     data,
+		count:    count,
+		keys:     data[mappedIndexHeaderSize:keysEnd],
+		offsets:  data[keysEnd:offsetsEnd],
+		payloads: data[offsetsEnd:],
+	}
+
+	if mi.offset(count) != uint64(len(mi.payloads)) {
+		return nil, fmt.Errorf("mapped-index payloads are not the expected size")
+	}
+
+	return mi, nil
+}
+
+// Close unmaps the file. The index can not be used afterward.
+func (mi *MappedIndex) Close() (err error) {
+	if mi.unmap == nil {
+		return nil
+	}
+
+	unmap := mi.unmap
+
+	mi.unmap = nil
+	mi.data = nil
+	mi.keys = nil
+	mi.offsets = nil
+	mi.payloads = nil
+	mi.count = 0
+
+	return unmap()
+}
+
+// Len returns the number of entries.
+func (mi *MappedIndex) Len() int {
+	return mi.count
+}
+
+func (mi *MappedIndex) key(i int) int64 {
+	return int64(binary.LittleEndian.Uint64(mi.keys[i*8:]))
+}
+
+func (mi *MappedIndex) offset(i int) uint64 {
+	return binary.LittleEndian.Uint64(mi.offsets[i*8:])
+}
+
+// Time returns the time of the entry at the given position.
+func (mi *MappedIndex) Time(i int) time.Time {
+	return time.Unix(0, mi.key(i)).UTC()
+}
+
+// Items decodes the items of the entry at the given position.
+func (mi *MappedIndex) Items(i int) (items []interface{}, err error) {
+	start := mi.offset(i)
+	end := mi.offset(i + 1)
+
+	if start > end || end > uint64(len(mi.payloads)) {
+		return nil, fmt.Errorf("mapped-index entry (%d) not valid", i)
+	}
+
+	return decodeStoreItems(mi.payloads[start:end], mi.codec)
+}
+
+// Entry returns the time and decoded items of the entry at the given
+// position.
+func (mi *MappedIndex) Entry(i int) (te TimeEntry, err error) {
+	items, err := mi.Items(i)
+	if err != nil {
+		return te, err
+	}
+
+	te = TimeEntry{
+		Time:  mi.Time(i),
+		Items: items,
+	}
+
+	return te, nil
+}
+
+// clampUnixNano returns the time as nanoseconds since the epoch, clamped to
+// the representable range.
+func clampUnixNano(t time.Time) int64 {
+	n, err := toUnixNano(t)
+	if err == nil {
+		return n
+	} else if t.Before(time.Unix(0, 0)) {
+		return math.MinInt64
+	}
+
+	return math.MaxInt64
+}
+
+// Search returns the position of the first entry with a time equal-to or
+// greater-than the given time (as `TimeSlice.Search`).
+func (mi *MappedIndex) Search(t time.Time) int {
+	n := clampUnixNano(t)
+
+	return sort.Search(mi.count, func(i int) bool {
+		return mi.key(i) >= n
+	})
+}
+
+// SearchNearest calls the callback with every time that is within tolerance
+// of the given time (as `TimeSlice.SearchNearest`).
+func (mi *MappedIndex) SearchNearest(t time.Time, tolerance time.Duration, cb func(t time.Time) error) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if mi.count == 0 {
+		return ErrNotFound
+	}
+
+	for i := mi.Search(t.Add(-tolerance)); i < mi.count; i++ {
+		current := mi.Time(i)
+		if AbsoluteDistance(current, t) > tolerance {
+			break
+		}
+
+		if err := cb(current); err != nil {
+			panic(err)
+		}
+	}
+
+	return nil
+}
+
+// Range calls the callback with every entry in [from, to).
+func (mi *MappedIndex) Range(from, to time.Time, cb func(te TimeEntry) error) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	toN := clampUnixNano(to)
+
+	for i := mi.Search(from); i < mi.count && mi.key(i) < toN; i++ {
+		te, err := mi.Entry(i)
+		log.PanicIf(err)
+
+		if err := cb(te); err != nil {
+			panic(err)
+		}
+	}
+
+	return nil
+}
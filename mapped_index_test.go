@@ -0,0 +1,218 @@
+package timeindex
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getTestMappedIndex(ts TimeSlice) (mi *MappedIndex, cleanup func()) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	filepath := path.Join(dir, "index.tixm")
+
+	err = WriteMappedIndexFile(filepath, ts, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	mi, err = OpenMappedIndex(filepath, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	cleanup = func() {
+		mi.Close()
+		os.RemoveAll(dir)
+	}
+
+	return mi, cleanup
+}
+
+func TestMappedIndex_Search(t *testing.T) {
+	time1, err := time.Parse(time.RFC3339, "2016-12-02T08:05:44Z")
+	log.PanicIf(err)
+
+	time2, err := time.Parse(time.RFC3339, "2016-12-02T09:05:44Z")
+	log.PanicIf(err)
+
+	time3, err := time.Parse(time.RFC3339, "2016-12-02T10:05:44Z")
+	log.PanicIf(err)
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(time2, "b")
+	ts = ts.Add(time3, nil)
+	ts = ts.Add(time1, "a1")
+	ts = ts.Add(time1, "a2")
+
+	mi, cleanup := getTestMappedIndex(ts)
+	defer cleanup()
+
+	if mi.Len() != 3 {
+		t.Fatalf("Length not correct: (%d)", mi.Len())
+	}
+
+	if mi.Search(time1) != 0 || mi.Search(time2) != 1 || mi.Search(time3) != 2 {
+		t.Fatalf("Times didn't search correctly")
+	} else if mi.Search(time1.Add(time.Minute)) != 1 || mi.Search(time3.Add(time.Minute)) != 3 {
+		t.Fatalf("In-between times didn't search correctly")
+	} else if mi.Search(time.Time{}) != 0 || mi.Search(time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)) != 3 {
+		t.Fatalf("Out-of-range times didn't search correctly")
+	}
+
+	if mi.Time(1).Equal(time2) == false {
+		t.Fatalf("Time not correct: [%s]", mi.Time(1))
+	}
+
+	items, err := mi.Items(0)
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(items, []interface{}{"a1", "a2"}) == false {
+		t.Fatalf("Items not correct: %v", items)
+	}
+
+	items, err = mi.Items(2)
+	log.PanicIf(err)
+
+	if len(items) != 0 {
+		t.Fatalf("Expected no items: %v", items)
+	}
+}
+
+func TestMappedIndex_SearchNearest(t *testing.T) {
+	ts := make(TimeSlice, 0)
+	for _, phrase := range []string{"08:05:44", "08:06:45", "08:07:46", "08:12:47", "08:13:48", "08:14:49", "08:26:50"} {
+		current, err := time.Parse(time.RFC3339, "2016-12-02T"+phrase+"Z")
+		log.PanicIf(err)
+
+		ts = ts.Add(current, nil)
+	}
+
+	mi, cleanup := getTestMappedIndex(ts)
+	defer cleanup()
+
+	queries := []string{"08:05:44", "08:10:00", "08:11:00", "08:20:00", "09:00:00"}
+	tolerances := []time.Duration{0, time.Minute, time.Minute * 3, time.Minute * 8}
+
+	for _, phrase := range queries {
+		q, err := time.Parse(time.RFC3339, "2016-12-02T"+phrase+"Z")
+		log.PanicIf(err)
+
+		for _, tolerance := range tolerances {
+			expected := make([]time.Time, 0)
+			for _, te := range ts {
+				if AbsoluteDistance(te.Time, q) <= tolerance {
+					expected = append(expected, te.Time)
+				}
+			}
+
+			actual := make([]time.Time, 0)
+			err := mi.SearchNearest(q, tolerance, func(t time.Time) error {
+				actual = append(actual, t)
+				return nil
+			})
+
+			log.PanicIf(err)
+
+			if len(actual) != len(expected) {
+				t.Fatalf("Nearest for [%s] within (%s) not correct: %v != %v", phrase, tolerance, actual, expected)
+			}
+
+			for i := range actual {
+				if actual[i].Equal(expected[i]) == false {
+					t.Fatalf("Nearest for [%s] within (%s) not correct: %v != %v", phrase, tolerance, actual, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestMappedIndex_Range(t *testing.T) {
+	epoch := getTestEpoch()
+
+	ts := make(TimeSlice, 0)
+	for i := 0; i < 100; i++ {
+		ts = append(ts, TimeEntry{Time: epoch.Add(time.Minute * time.Duration(i)), Items: []interface{}{float64(i)}})
+	}
+
+	mi, cleanup := getTestMappedIndex(ts)
+	defer cleanup()
+
+	found := make(TimeSlice, 0)
+	err := mi.Range(epoch.Add(time.Minute*10), epoch.Add(time.Minute*20), func(te TimeEntry) error {
+		found = append(found, te)
+		return nil
+	})
+
+	log.PanicIf(err)
+
+	if len(found) != 10 {
+		t.Fatalf("Range count not correct: (%d)", len(found))
+	}
+
+	for i, te := range found {
+		if te.Time.Equal(ts[10+i].Time) == false || reflect.DeepEqual(te.Items, ts[10+i].Items) == false {
+			t.Fatalf("Range entry (%d) not correct: %v", i, te)
+		}
+	}
+}
+
+func TestMappedIndex_Empty(t *testing.T) {
+	mi, cleanup := getTestMappedIndex(TimeSlice{})
+	defer cleanup()
+
+	if mi.Len() != 0 {
+		t.Fatalf("Expected no entries.")
+	}
+
+	err := mi.SearchNearest(time.Now(), time.Hour, func(t time.Time) error {
+		return nil
+	})
+
+	if err != ErrNotFound {
+		t.Fatalf("Expected not-found: %v", err)
+	}
+}
+
+func TestWriteMappedIndex_Unsorted(t *testing.T) {
+	epoch := getTestEpoch()
+
+	ts := TimeSlice{
+		TimeEntry{Time: epoch.Add(time.Minute)},
+		TimeEntry{Time: epoch},
+	}
+
+	err := WriteMappedIndex(ioutil.Discard, ts, DefaultJsonItemCodec)
+	if err == nil {
+		t.Fatalf("Expected error for unsorted slice.")
+	}
+}
+
+func TestOpenMappedIndex_Truncated(t *testing.T) {
+	epoch := getTestEpoch()
+
+	ts := TimeSlice{TimeEntry{Time: epoch, Items: []interface{}{"a"}}}
+
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	filepath := path.Join(dir, "index.tixm")
+
+	err = WriteMappedIndexFile(filepath, ts, DefaultJsonItemCodec)
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	err = ioutil.WriteFile(filepath, data[:len(data)-1], 0644)
+	log.PanicIf(err)
+
+	_, err = OpenMappedIndex(filepath, DefaultJsonItemCodec)
+	if err == nil {
+		t.Fatalf("Expected error for truncated file.")
+	}
+}
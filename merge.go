@@ -0,0 +1,157 @@
+package timeindex
+
+import (
+	"container/heap"
+)
+
+// mergeCursor is the position in one of the slices being merged.
+type mergeCursor struct {
+	slice    int
+	position int
+}
+
+// mergeHeap orders cursors by the element that they point to and then by
+// slice (so that the items of equal elements stay in the order of the
+// slices).
+type mergeHeap struct {
+	cursors []mergeCursor
+	compare func(a, b mergeCursor) int
+}
+
+func (mh *mergeHeap) Len() int {
+	return len(mh.cursors)
+}
+
+func (mh *mergeHeap) Less(i, j int) bool {
+	a, b := mh.cursors[i], mh.cursors[j]
+	if c := mh.compare(a, b); c != 0 {
+		return c < 0
+	}
+
+	return a.slice < b.slice
+}
+
+func (mh *mergeHeap) Swap(i, j int) {
+	mh.cursors[i], mh.cursors[j] = mh.cursors[j], mh.cursors[i]
+}
+
+func (mh *mergeHeap) Push(x interface{}) {
+	mh.cursors = append(mh.cursors, x.(mergeCursor))
+}
+
+func (mh *mergeHeap) Pop() interface{} {
+	last := mh.cursors[len(mh.cursors)-1]
+	mh.cursors = mh.cursors[:len(mh.cursors)-1]
+
+	return last
+}
+
+// mergeSorted visits the elements of k sorted slices (of the given lengths)
+// in order, calling `emit` with the slice and position of each.
+func mergeSorted(lengths []int, compare func(a, b mergeCursor) int, emit func(mc mergeCursor)) {
+	mh := &mergeHeap{
+		cursors: make([]mergeCursor, 0, len(lengths)),
+		compare: compare,
+	}
+
+	for i, length := range lengths {
+		if length > 0 {
+			mh.cursors = append(mh.cursors, mergeCursor{slice: i})
+		}
+	}
+
+	heap.Init(mh)
+
+	for mh.Len() > 0 {
+		mc := mh.cursors[0]
+		emit(mc)
+
+		if mc.position+1 < lengths[mc.slice] {
+			mh.cursors[0].position++
+			heap.Fix(mh, 0)
+		} else {
+			heap.Pop(mh)
+		}
+	}
+}
+
+// MergeTimeSlices merges sorted slices into a new sorted slice using a k-way
+// merge. The items of equal times are combined (in the order of the slices).
+// The inputs are not modified.
+func MergeTimeSlices(slices ...TimeSlice) (merged TimeSlice) {
+	lengths := make([]int, len(slices))
+	total := 0
+	for i, ts := range slices {
+		lengths[i] = len(ts)
+		total += len(ts)
+	}
+
+	compare := func(a, b mergeCursor) int {
+		ta := slices[a.slice][a.position].Time
+		tb := slices[b.slice][b.position].Time
+
+		if ta.Before(tb) == true {
+			return -1
+		} else if ta.After(tb) == true {
+			return 1
+		}
+
+		return 0
+	}
+
+	merged = make(TimeSlice, 0, total)
+
+	emit := func(mc mergeCursor) {
+		te := slices[mc.slice][mc.position]
+
+		last := len(merged) - 1
+		if last >= 0 && merged[last].Time.Equal(te.Time) == true {
+			merged[last].Items = append(merged[last].Items, te.Items...)
+			return
+		}
+
+		// Copy the items so that combining doesn't write into an input.
+		te.Items = append([]interface{}{}, te.Items...)
+		merged = append(merged, te)
+	}
+
+	mergeSorted(lengths, compare, emit)
+
+	return merged
+}
+
+// MergeTimeIntervalSlices merges sorted slices into a new slice sorted by
+// start and then stop time (the order that `TimeIntervalSlice.Add` keeps)
+// using a k-way merge. The items of equal intervals are combined. The inputs
+// are not modified.
+func MergeTimeIntervalSlices(slices ...TimeIntervalSlice) (merged TimeIntervalSlice) {
+	lengths := make([]int, len(slices))
+	total := 0
+	for i, tis := range slices {
+		lengths[i] = len(tis)
+		total += len(tis)
+	}
+
+	compare := func(a, b mergeCursor) int {
+		return compareIntervals(slices[a.slice][a.position], slices[b.slice][b.position])
+	}
+
+	merged = make(TimeIntervalSlice, 0, total)
+
+	emit := func(mc mergeCursor) {
+		ti := slices[mc.slice][mc.position]
+
+		last := len(merged) - 1
+		if last >= 0 && compareIntervals(merged[last], ti) == 0 {
+			merged[last].Items = append(merged[last].Items, ti.Items...)
+			return
+		}
+
+		ti.Items = append([]interface{}{}, ti.Items...)
+		merged = append(merged, ti)
+	}
+
+	mergeSorted(lengths, compare, emit)
+
+	return merged
+}
@@ -0,0 +1,115 @@
+package timeindex
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestMergeTimeSlices(t *testing.T) {
+	a := make(TimeSlice, 0)
+	a = a.Add(getTestSecond(1), "a1")
+	a = a.Add(getTestSecond(5), "a5")
+
+	b := make(TimeSlice, 0)
+	b = b.Add(getTestSecond(0), "b0")
+	b = b.Add(getTestSecond(5), "b5")
+	b = b.Add(getTestSecond(9), nil)
+
+	c := make(TimeSlice, 0)
+	c = c.Add(getTestSecond(5), "c5")
+
+	merged := MergeTimeSlices(a, TimeSlice{}, b, c)
+
+	expected := []struct {
+		seconds int
+		items   []interface{}
+	}{
+		{0, []interface{}{"b0"}},
+		{1, []interface{}{"a1"}},
+		{5, []interface{}{"a5", "b5", "c5"}},
+		{9, []interface{}{}},
+	}
+
+	if len(merged) != len(expected) {
+		t.Fatalf("Merged count not correct: %v", merged)
+	}
+
+	for i, e := range expected {
+		if merged[i].Time.Equal(getTestSecond(e.seconds)) == false || reflect.DeepEqual(merged[i].Items, e.items) == false {
+			t.Fatalf("Entry (%d) not correct: %v", i, merged[i])
+		}
+	}
+
+	// The inputs are untouched.
+	if len(a[1].Items) != 1 || len(b[1].Items) != 1 {
+		t.Fatalf("Inputs were modified.")
+	}
+}
+
+func TestMergeTimeSlices_MatchesAdd(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	slices := make([]TimeSlice, 5)
+	expected := make(TimeSlice, 0)
+
+	for i := 0; i < 1000; i++ {
+		when := getTestSecond(r.Intn(300))
+		shard := r.Intn(len(slices))
+
+		slices[shard] = slices[shard].Add(when, i)
+		expected = expected.Add(when, i)
+	}
+
+	merged := MergeTimeSlices(slices...)
+
+	if len(merged) != len(expected) {
+		t.Fatalf("Merged count not correct: (%d) != (%d)", len(merged), len(expected))
+	}
+
+	for i, te := range expected {
+		if merged[i].Time.Equal(te.Time) == false || len(merged[i].Items) != len(te.Items) {
+			t.Fatalf("Entry (%d) not correct: %v != %v", i, merged[i], te)
+		}
+	}
+}
+
+func TestMergeTimeSlices_None(t *testing.T) {
+	if merged := MergeTimeSlices(); len(merged) != 0 {
+		t.Fatalf("Expected empty slice: %v", merged)
+	}
+}
+
+func TestMergeTimeIntervalSlices(t *testing.T) {
+	a := make(TimeIntervalSlice, 0)
+	a = a.Add(getTestSecond(0), getTestSecond(10), "a")
+	a = a.Add(getTestSecond(5), getTestSecond(6), "a")
+
+	b := make(TimeIntervalSlice, 0)
+	b = b.Add(getTestSecond(0), getTestSecond(5), "b")
+	b = b.Add(getTestSecond(0), getTestSecond(10), "b")
+
+	merged := MergeTimeIntervalSlices(a, b)
+
+	expected := TimeIntervalSlice{
+		{From: getTestSecond(0), To: getTestSecond(5), Items: []interface{}{"b"}},
+		{From: getTestSecond(0), To: getTestSecond(10), Items: []interface{}{"a", "b"}},
+		{From: getTestSecond(5), To: getTestSecond(6), Items: []interface{}{"a"}},
+	}
+
+	if reflect.DeepEqual(merged, expected) == false {
+		t.Fatalf("Merged intervals not correct: %v", merged)
+	}
+
+	// Merging gives the same result as adding every interval to one slice.
+	added := make(TimeIntervalSlice, 0)
+	for _, tis := range []TimeIntervalSlice{a, b} {
+		for _, ti := range tis {
+			added = added.Add(ti.From, ti.To, ti.Items[0])
+		}
+	}
+
+	if reflect.DeepEqual(merged, added) == false {
+		t.Fatalf("Merged intervals not ordered as by Add: %v != %v", merged, added)
+	}
+}
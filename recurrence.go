@@ -0,0 +1,530 @@
+package timeindex
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// maxEmptyRecurrencePeriods is how many consecutive periods without any
+	// occurrences we'll walk before deciding that a rule can never match
+	// again (e.g. "FREQ=DAILY;INTERVAL=7;BYDAY=MO" starting on a Tuesday).
+	maxEmptyRecurrencePeriods = 1000
+)
+
+// RecurrenceFrequency is the period that a `Recurrence` repeats at.
+type RecurrenceFrequency int
+
+const (
+	RecurHourly RecurrenceFrequency = iota
+	RecurDaily
+	RecurWeekly
+	RecurMonthly
+	RecurYearly
+)
+
+var (
+	recurrenceFrequencies = map[string]RecurrenceFrequency{
+		"HOURLY":  RecurHourly,
+		"DAILY":   RecurDaily,
+		"WEEKLY":  RecurWeekly,
+		"MONTHLY": RecurMonthly,
+		"YEARLY":  RecurYearly,
+	}
+
+	recurrenceWeekdays = map[string]time.Weekday{
+		"SU": time.Sunday,
+		"MO": time.Monday,
+		"TU": time.Tuesday,
+		"WE": time.Wednesday,
+		"TH": time.Thursday,
+		"FR": time.Friday,
+		"SA": time.Saturday,
+	}
+)
+
+// Recurrence is a repeating interval described by a subset of an RFC 5545
+// RRULE. Occurrences are computed in the location of `Start`, so "02:00"
+// stays 02:00 local time across DST transitions.
+type Recurrence struct {
+	// Start is the first possible occurrence (DTSTART). It is only an
+	// occurrence if it matches the rule. The minute, second, and hour (unless
+	// `ByHour` is given), weekday (unless `ByDay` is given), and day of the
+	// month or year of every occurrence are taken from it.
+	Start time.Time
+
+	// Duration is the length of every occurrence.
+	Duration time.Duration
+
+	Frequency RecurrenceFrequency
+
+	// Interval is the number of periods between repetitions. Defaults to 1.
+	Interval int
+
+	// ByDay restricts occurrences to the given weekdays. With `RecurMonthly`
+	// and `RecurYearly` it selects every such weekday in the month or year.
+	ByDay []time.Weekday
+
+	// ByHour restricts (or, for daily and longer periods, expands)
+	// occurrences to the given hours.
+	ByHour []int
+
+	// Count, if not zero, is the number of occurrences (including the ones
+	// removed by `ExDates`).
+	Count int
+
+	// Until, if not zero, is the last time that an occurrence can start.
+	Until time.Time
+
+	// ExDates are occurrence start times to skip.
+	ExDates []time.Time
+
+	// WeekStart is the first day of the week (WKST), which decides which
+	// weeks `Interval` skips for `RecurWeekly`. Nil means Monday, as in RFC
+	// 5545.
+	WeekStart *time.Weekday
+
+	// Items are attached to every occurrence.
+	Items []interface{}
+}
+
+// ParseRecurrence parses an RRULE (e.g. "FREQ=WEEKLY;BYDAY=TU;BYHOUR=2",
+// optionally prefixed with "RRULE:"). FREQ, INTERVAL, BYDAY (without
+// ordinals), BYHOUR, COUNT, UNTIL, WKST, and (as an extension) EXDATE with
+// comma-separated times are supported. Times without a zone are in the
+// location of `start`.
+func ParseRecurrence(rule string, start time.Time, duration time.Duration) (r Recurrence, err error) {
+	r = Recurrence{
+		Start:    start,
+		Duration: duration,
+		Interval: 1,
+		Items:    []interface{}{},
+	}
+
+	rule = strings.TrimSpace(rule)
+	if strings.HasPrefix(strings.ToUpper(rule), "RRULE:") == true {
+		rule = rule[len("RRULE:"):]
+	}
+
+	hasFrequency := false
+
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return r, fmt.Errorf("recurrence part not valid: [%s]", part)
+		}
+
+		key := strings.ToUpper(kv[0])
+		value := strings.ToUpper(kv[1])
+
+		switch key {
+		case "FREQ":
+			frequency, found := recurrenceFrequencies[value]
+			if found == false {
+				return r, fmt.Errorf("recurrence frequency not supported: [%s]", value)
+			}
+
+			r.Frequency = frequency
+			hasFrequency = true
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(value); err != nil || r.Interval < 1 {
+				return r, fmt.Errorf("recurrence interval not valid: [%s]", value)
+			}
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(value); err != nil || r.Count < 1 {
+				return r, fmt.Errorf("recurrence count not valid: [%s]", value)
+			}
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				weekday, found := recurrenceWeekdays[code]
+				if found == false {
+					return r, fmt.Errorf("recurrence weekday not supported: [%s]", code)
+				}
+
+				r.ByDay = append(r.ByDay, weekday)
+			}
+		case "BYHOUR":
+			for _, raw := range strings.Split(value, ",") {
+				hour, err := strconv.Atoi(raw)
+				if err != nil || hour < 0 || hour > 23 {
+					return r, fmt.Errorf("recurrence hour not valid: [%s]", raw)
+				}
+
+				r.ByHour = append(r.ByHour, hour)
+			}
+		case "WKST":
+			weekday, found := recurrenceWeekdays[value]
+			if found == false {
+				return r, fmt.Errorf("recurrence week start not valid: [%s]", value)
+			}
+
+			r.WeekStart = &weekday
+		case "UNTIL":
+			if r.Until, err = parseRecurrenceTime(value, start.Location()); err != nil {
+				return r, err
+			}
+
+			// A date includes the whole day.
+			if len(value) == len("20060102") {
+				r.Until = r.Until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+		case "EXDATE":
+			for _, raw := range strings.Split(value, ",") {
+				exDate, err := parseRecurrenceTime(raw, start.Location())
+				if err != nil {
+					return r, err
+				}
+
+				r.ExDates = append(r.ExDates, exDate)
+			}
+		default:
+			return r, fmt.Errorf("recurrence part not supported: [%s]", key)
+		}
+	}
+
+	if hasFrequency == false {
+		return r, fmt.Errorf("recurrence has no frequency: [%s]", rule)
+	} else if duration <= 0 {
+		return r, fmt.Errorf("recurrence duration must be positive: [%s]", duration)
+	} else if r.Count != 0 && r.Until.IsZero() == false {
+		return r, fmt.Errorf("recurrence can not have both COUNT and UNTIL: [%s]", rule)
+	}
+
+	return r, nil
+}
+
+// parseRecurrenceTime parses an RFC 5545 date or date-time (or an ISO 8601
+// time).
+func parseRecurrenceTime(s string, location *time.Location) (t time.Time, err error) {
+	return parseIsoTime(s, location)
+}
+
+func (r Recurrence) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+
+	return r.Interval
+}
+
+func (r Recurrence) weekStart() time.Weekday {
+	if r.WeekStart == nil {
+		return time.Monday
+	}
+
+	return *r.WeekStart
+}
+
+// weekOffset returns how many days into the week the weekday is.
+func (r Recurrence) weekOffset(weekday time.Weekday) int {
+	return (int(weekday) - int(r.weekStart()) + 7) % 7
+}
+
+// periodStart returns the start of the kth period.
+func (r Recurrence) periodStart(k int) time.Time {
+	location := r.Start.Location()
+	year, month, day := r.Start.Date()
+	n := k * r.interval()
+
+	switch r.Frequency {
+	case RecurHourly:
+		return r.Start.Add(time.Duration(n) * time.Hour)
+	case RecurDaily:
+		return time.Date(year, month, day+n, 0, 0, 0, 0, location)
+	case RecurWeekly:
+		return time.Date(year, month, day-r.weekOffset(r.Start.Weekday())+n*7, 0, 0, 0, 0, location)
+	case RecurMonthly:
+		return time.Date(year, month+time.Month(n), 1, 0, 0, 0, 0, location)
+	case RecurYearly:
+		return time.Date(year+n, time.January, 1, 0, 0, 0, 0, location)
+	}
+
+	log.Panicf("recurrence frequency (%d) not valid", r.Frequency)
+	return time.Time{}
+}
+
+// periodOf returns a period at or before the one containing `t`.
+func (r Recurrence) periodOf(t time.Time) int {
+	startYear, startMonth, startDay := r.Start.Date()
+	year, month, day := t.In(r.Start.Location()).Date()
+
+	days := int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Sub(time.Date(startYear, startMonth, startDay, 0, 0, 0, 0, time.UTC)) / (time.Hour * 24))
+
+	var units int
+
+	switch r.Frequency {
+	case RecurHourly:
+		units = int(t.Sub(r.Start) / time.Hour)
+	case RecurDaily:
+		units = days
+	case RecurWeekly:
+		units = (days + r.weekOffset(r.Start.Weekday())) / 7
+	case RecurMonthly:
+		units = (year-startYear)*12 + int(month-startMonth)
+	case RecurYearly:
+		units = year - startYear
+	}
+
+	// Step back one more period to be safe with regard to rounding.
+	k := units/r.interval() - 1
+	if k < 0 {
+		k = 0
+	}
+
+	return k
+}
+
+func (r Recurrence) hasWeekday(weekday time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+
+	for _, day := range r.ByDay {
+		if day == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+// candidates returns the times in the kth period that match the rule, in
+// order. `Count`, `Until`, and `ExDates` are not applied.
+func (r Recurrence) candidates(k int) []time.Time {
+	location := r.Start.Location()
+	periodStart := r.periodStart(k)
+
+	if r.Frequency == RecurHourly {
+		if r.hasWeekday(periodStart.Weekday()) == false {
+			return nil
+		}
+
+		if len(r.ByHour) > 0 {
+			found := false
+			for _, hour := range r.ByHour {
+				if hour == periodStart.Hour() {
+					found = true
+					break
+				}
+			}
+
+			if found == false {
+				return nil
+			}
+		}
+
+		return []time.Time{periodStart}
+	}
+
+	hours := r.ByHour
+	if len(hours) == 0 {
+		hours = []int{r.Start.Hour()}
+	}
+
+	year, month, day := periodStart.Date()
+
+	// The days (relative to the start of the period) to consider.
+	var days []int
+
+	switch r.Frequency {
+	case RecurDaily:
+		days = []int{0}
+	case RecurWeekly:
+		for i := 0; i < 7; i++ {
+			weekday := time.Weekday((int(r.weekStart()) + i) % 7)
+			if (len(r.ByDay) == 0 && weekday == r.Start.Weekday()) || (len(r.ByDay) > 0 && r.hasWeekday(weekday) == true) {
+				days = append(days, i)
+			}
+		}
+	case RecurMonthly, RecurYearly:
+		end := periodStart.AddDate(0, 1, 0)
+		if r.Frequency == RecurYearly {
+			end = periodStart.AddDate(1, 0, 0)
+		}
+
+		if len(r.ByDay) > 0 {
+			for i := 0; time.Date(year, month, day+i, 0, 0, 0, 0, location).Before(end) == true; i++ {
+				days = append(days, i)
+			}
+		} else {
+			startMonth := month
+			if r.Frequency == RecurYearly {
+				startMonth = r.Start.Month()
+			}
+
+			// Skip months (or years) that don't have the start's day (e.g.
+			// the 31st or February 29th).
+			date := time.Date(year, startMonth, r.Start.Day(), 0, 0, 0, 0, location)
+			if date.Month() == startMonth {
+				days = []int{int(time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC).Sub(time.Date(year, month, day, 0, 0, 0, 0, time.UTC)) / (time.Hour * 24))}
+			}
+		}
+	}
+
+	matches := make([]time.Time, 0, len(days)*len(hours))
+	for _, i := range days {
+		date := time.Date(year, month, day+i, 0, 0, 0, 0, location)
+		if r.Frequency != RecurWeekly && r.hasWeekday(date.Weekday()) == false {
+			continue
+		}
+
+		for _, hour := range hours {
+			t := time.Date(date.Year(), date.Month(), date.Day(), hour, r.Start.Minute(), r.Start.Second(), r.Start.Nanosecond(), location)
+			matches = append(matches, t)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Before(matches[j])
+	})
+
+	return matches
+}
+
+func (r Recurrence) isExcluded(t time.Time) bool {
+	for _, exDate := range r.ExDates {
+		if exDate.Equal(t) == true {
+			return true
+		}
+	}
+
+	return false
+}
+
+// walk calls `cb` with the start of every occurrence, in order, until it
+// returns false or there are no more occurrences that start by `to`. Without
+// a count, the periods before the one containing `from` are skipped (they
+// don't have to be counted).
+func (r Recurrence) walk(from, to time.Time, cb func(t time.Time) bool) {
+	first := 0
+	if r.Count == 0 {
+		first = r.periodOf(from)
+	}
+
+	n := 0
+	empty := 0
+
+	for k := first; r.periodStart(k).After(to) == false; k++ {
+		candidates := r.candidates(k)
+		if len(candidates) == 0 {
+			empty++
+			if empty > maxEmptyRecurrencePeriods {
+				return
+			}
+
+			continue
+		}
+
+		empty = 0
+
+		for _, t := range candidates {
+			if t.Before(r.Start) == true {
+				continue
+			} else if t.After(to) == true {
+				return
+			} else if r.Until.IsZero() == false && t.After(r.Until) == true {
+				return
+			}
+
+			n++
+			if r.Count != 0 && n > r.Count {
+				return
+			}
+
+			if r.isExcluded(t) == true {
+				continue
+			}
+
+			if cb(t) == false {
+				return
+			}
+		}
+	}
+}
+
+func (r Recurrence) occurrence(t time.Time) TimeInterval {
+	items := make([]interface{}, len(r.Items))
+	copy(items, r.Items)
+
+	return TimeInterval{
+		From:  t,
+		To:    t.Add(r.Duration),
+		Items: items,
+	}
+}
+
+// Expand returns the occurrences that overlap [from, to] (both inclusive). It
+// panics if `Duration` isn't positive.
+func (r Recurrence) Expand(from, to time.Time) TimeIntervalSlice {
+	if r.Duration <= 0 {
+		log.Panicf("recurrence duration must be positive: [%s]", r.Duration)
+	}
+
+	tis := make(TimeIntervalSlice, 0)
+
+	// The earliest occurrence that could still be running at `from`.
+	earliest := from.Add(-r.Duration)
+
+	r.walk(earliest, to, func(t time.Time) bool {
+		if t.Before(earliest) == false {
+			tis = append(tis, r.occurrence(t))
+		}
+
+		return true
+	})
+
+	return tis
+}
+
+// Search calls the callback with the occurrences that contain the given time
+// (including at their end times, as with `TimeIntervalSlice.Search`). The
+// occurrences are computed as needed rather than materialized. Without a
+// `Count`, the periods before the time are skipped; with one, they have to be
+// walked (but not stored) on every call to count the earlier occurrences.
+func (r Recurrence) Search(t time.Time, cb func(ti TimeInterval) error) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if r.Duration <= 0 {
+		log.Panicf("recurrence duration must be positive: [%s]", r.Duration)
+	}
+
+	from := t.Add(-r.Duration)
+
+	r.walk(from, t, func(start time.Time) bool {
+		if start.Before(from) == true {
+			return true
+		}
+
+		err := cb(r.occurrence(start))
+		log.PanicIf(err)
+
+		return true
+	})
+
+	return nil
+}
+
+// SearchAndReturn returns the occurrences that contain the given time.
+func (r Recurrence) SearchAndReturn(t time.Time) (matches []TimeInterval) {
+	matches = make([]TimeInterval, 0)
+
+	cb := func(ti TimeInterval) (err error) {
+		matches = append(matches, ti)
+		return nil
+	}
+
+	if err := r.Search(t, cb); err != nil {
+		log.Panic(err)
+	}
+
+	return matches
+}
@@ -0,0 +1,367 @@
+package timeindex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getBerlin() *time.Location {
+	location, err := time.LoadLocation("Europe/Berlin")
+	log.PanicIf(err)
+
+	return location
+}
+
+func TestParseRecurrence(t *testing.T) {
+	start := time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC)
+
+	r, err := ParseRecurrence("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;BYHOUR=2,14;COUNT=10", start, time.Hour*2)
+	log.PanicIf(err)
+
+	if r.Frequency != RecurWeekly || r.Interval != 2 || r.Count != 10 || r.Duration != time.Hour*2 {
+		t.Fatalf("Recurrence not correct: %#v", r)
+	} else if len(r.ByDay) != 2 || r.ByDay[0] != time.Tuesday || r.ByDay[1] != time.Thursday {
+		t.Fatalf("Weekdays not correct: %v", r.ByDay)
+	} else if len(r.ByHour) != 2 || r.ByHour[0] != 2 || r.ByHour[1] != 14 {
+		t.Fatalf("Hours not correct: %v", r.ByHour)
+	}
+
+	r, err = ParseRecurrence("freq=daily;until=20240105;exdate=20240103T020000Z", start, time.Hour)
+	log.PanicIf(err)
+
+	if r.Until.Equal(time.Date(2024, 1, 5, 23, 59, 59, 999999999, time.UTC)) == false {
+		t.Fatalf("Until not correct: [%s]", r.Until)
+	} else if len(r.ExDates) != 1 || r.ExDates[0].Equal(time.Date(2024, 1, 3, 2, 0, 0, 0, time.UTC)) == false {
+		t.Fatalf("Excluded dates not correct: %v", r.ExDates)
+	}
+}
+
+func TestParseRecurrence_Invalid(t *testing.T) {
+	start := time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC)
+
+	rules := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=SECONDLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=x",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=DAILY;BYHOUR=24",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;COUNT=2;UNTIL=20240105",
+		"FREQ=WEEKLY;WKST=XX",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ",
+	}
+
+	for _, rule := range rules {
+		if _, err := ParseRecurrence(rule, start, time.Hour); err == nil {
+			t.Fatalf("Expected error for [%s].", rule)
+		}
+	}
+}
+
+func TestRecurrence_NonPositiveDuration(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	for _, duration := range []time.Duration{0, -time.Hour} {
+		if _, err := ParseRecurrence("FREQ=DAILY", start, duration); err == nil {
+			t.Fatalf("Expected error for duration [%s].", duration)
+		}
+
+		r := Recurrence{
+			Start:     start,
+			Duration:  duration,
+			Frequency: RecurDaily,
+		}
+
+		cb := func(ti TimeInterval) error {
+			t.Fatalf("Expected no occurrences: %v", ti)
+			return nil
+		}
+
+		if err := r.Search(start, cb); err == nil {
+			t.Fatalf("Expected search error for duration [%s].", duration)
+		}
+
+		func() {
+			defer func() {
+				if state := recover(); state == nil {
+					t.Fatalf("Expected expand to panic for duration [%s].", duration)
+				}
+			}()
+
+			r.Expand(start, start.AddDate(0, 0, 7))
+		}()
+	}
+}
+
+func TestRecurrence_Expand_WeeklyAcrossDst(t *testing.T) {
+	location := getBerlin()
+
+	// Every Tuesday 02:00-04:00 Europe/Berlin. Summer time starts on Sunday,
+	// 2024-03-31.
+	start := time.Date(2024, 3, 5, 2, 0, 0, 0, location)
+
+	r, err := ParseRecurrence("FREQ=WEEKLY;BYDAY=TU", start, time.Hour*2)
+	log.PanicIf(err)
+
+	r.Items = []interface{}{"maintenance"}
+
+	tis := r.Expand(time.Date(2024, 3, 1, 0, 0, 0, 0, location), time.Date(2024, 4, 10, 0, 0, 0, 0, location))
+
+	days := []int{5, 12, 19, 26, 2, 9}
+	if len(tis) != len(days) {
+		t.Fatalf("Occurrence count not correct: (%d)", len(tis))
+	}
+
+	for i, ti := range tis {
+		local := ti.From.In(location)
+		if local.Day() != days[i] || local.Hour() != 2 || local.Weekday() != time.Tuesday {
+			t.Fatalf("Occurrence (%d) not correct: [%s]", i, local)
+		} else if ti.To.Sub(ti.From) != time.Hour*2 || ti.Items[0] != "maintenance" {
+			t.Fatalf("Occurrence (%d) not correct: %v", i, ti)
+		}
+	}
+
+	// The offset changes from +01:00 to +02:00.
+	if tis[3].From.UTC().Hour() != 1 || tis[4].From.UTC().Hour() != 0 {
+		t.Fatalf("Occurrences not in local time: [%s] [%s]", tis[3].From.UTC(), tis[4].From.UTC())
+	}
+}
+
+func TestRecurrence_Expand_WeekStart(t *testing.T) {
+	// The example from RFC 5545: the week start decides which weeks are
+	// skipped.
+	start := time.Date(1997, 8, 5, 9, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		rule string
+		days []int
+	}{
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO", []int{5, 10, 19, 24}},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU", []int{5, 17, 19, 31}},
+
+		// Monday is the default.
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU", []int{5, 10, 19, 24}},
+	}
+
+	for _, c := range cases {
+		r, err := ParseRecurrence(c.rule, start, time.Hour)
+		log.PanicIf(err)
+
+		tis := r.Expand(start, start.AddDate(0, 1, 0))
+		if len(tis) != len(c.days) {
+			t.Fatalf("Occurrences for [%s] not correct: %v", c.rule, tis)
+		}
+
+		for i, ti := range tis {
+			if ti.From.Day() != c.days[i] {
+				t.Fatalf("Occurrence (%d) for [%s] not correct: [%s]", i, c.rule, ti.From)
+			}
+		}
+
+		// Searching skips to the week and has to agree.
+		for _, ti := range tis {
+			if matches := r.SearchAndReturn(ti.From); len(matches) != 1 {
+				t.Fatalf("Search for [%s] not correct: %v", ti.From, matches)
+			}
+		}
+	}
+}
+
+func TestRecurrence_Expand_DefaultWeekStart(t *testing.T) {
+	start := time.Date(1997, 8, 5, 9, 0, 0, 0, time.UTC)
+
+	// A recurrence that isn't parsed also has Monday-based weeks.
+	r := Recurrence{
+		Start:     start,
+		Duration:  time.Hour,
+		Frequency: RecurWeekly,
+		Interval:  2,
+		Count:     4,
+		ByDay:     []time.Weekday{time.Tuesday, time.Sunday},
+	}
+
+	tis := r.Expand(start, start.AddDate(0, 1, 0))
+
+	expected := []int{5, 10, 19, 24}
+	if len(tis) != len(expected) {
+		t.Fatalf("Occurrences not correct: %v", tis)
+	}
+
+	for i, ti := range tis {
+		if ti.From.Day() != expected[i] {
+			t.Fatalf("Occurrence (%d) not correct: [%s]", i, ti.From)
+		}
+	}
+}
+
+func TestRecurrence_Expand_Overlapping(t *testing.T) {
+	start := time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)
+
+	r, err := ParseRecurrence("FREQ=DAILY", start, time.Hour*4)
+	log.PanicIf(err)
+
+	// The occurrence that started the previous evening is still running.
+	tis := r.Expand(time.Date(2024, 1, 5, 1, 0, 0, 0, time.UTC), time.Date(2024, 1, 5, 23, 0, 0, 0, time.UTC))
+
+	if len(tis) != 2 || tis[0].From.Equal(time.Date(2024, 1, 4, 22, 0, 0, 0, time.UTC)) == false || tis[1].From.Equal(time.Date(2024, 1, 5, 22, 0, 0, 0, time.UTC)) == false {
+		t.Fatalf("Occurrences not correct: %v", tis)
+	}
+}
+
+func TestRecurrence_Expand_CountAndExDates(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	r, err := ParseRecurrence("FREQ=DAILY;BYDAY=MO,WE,FR;BYHOUR=9,17;COUNT=5;EXDATE=20240103T090000Z", start, time.Hour)
+	log.PanicIf(err)
+
+	tis := r.Expand(start, start.AddDate(1, 0, 0))
+
+	// Mon 09, Mon 17, (Wed 09 excluded but counted), Wed 17, Fri 09.
+	expected := []time.Time{
+		time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 17, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC),
+	}
+
+	if len(tis) != len(expected) {
+		t.Fatalf("Occurrences not correct: %v", tis)
+	}
+
+	for i, ti := range tis {
+		if ti.From.Equal(expected[i]) == false {
+			t.Fatalf("Occurrence (%d) not correct: [%s]", i, ti.From)
+		}
+	}
+
+	// With a count, the window can be anywhere.
+	if tis := r.Expand(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), start.AddDate(1, 0, 0)); len(tis) != 1 {
+		t.Fatalf("Occurrences not correct: %v", tis)
+	}
+}
+
+func TestRecurrence_Expand_Until(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	r, err := ParseRecurrence("FREQ=HOURLY;INTERVAL=6;UNTIL=20240102T000000Z", start, time.Minute)
+	log.PanicIf(err)
+
+	if tis := r.Expand(start, start.AddDate(0, 1, 0)); len(tis) != 5 {
+		t.Fatalf("Occurrences not correct: %v", tis)
+	}
+}
+
+func TestRecurrence_Expand_Monthly(t *testing.T) {
+	// The 31st is skipped in shorter months.
+	start := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	r, err := ParseRecurrence("FREQ=MONTHLY", start, time.Hour)
+	log.PanicIf(err)
+
+	tis := r.Expand(start, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	months := []time.Month{time.January, time.March, time.May}
+	if len(tis) != len(months) {
+		t.Fatalf("Occurrences not correct: %v", tis)
+	}
+
+	for i, ti := range tis {
+		if ti.From.Month() != months[i] || ti.From.Day() != 31 {
+			t.Fatalf("Occurrence (%d) not correct: [%s]", i, ti.From)
+		}
+	}
+
+	// Every Friday in the month.
+	r, err = ParseRecurrence("FREQ=MONTHLY;INTERVAL=2;BYDAY=FR", time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), time.Hour)
+	log.PanicIf(err)
+
+	tis = r.Expand(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	// March has five Fridays; April is skipped; May has five.
+	if len(tis) != 10 || tis[5].From.Equal(time.Date(2024, 5, 3, 8, 0, 0, 0, time.UTC)) == false {
+		t.Fatalf("Occurrences not correct: %v", tis)
+	}
+}
+
+func TestRecurrence_Expand_Yearly(t *testing.T) {
+	start := time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)
+
+	r, err := ParseRecurrence("FREQ=YEARLY", start, time.Hour*24)
+	log.PanicIf(err)
+
+	tis := r.Expand(start, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	if len(tis) != 3 || tis[1].From.Year() != 2024 || tis[2].From.Year() != 2028 {
+		t.Fatalf("Occurrences not correct: %v", tis)
+	}
+}
+
+func TestRecurrence_Expand_NeverMatches(t *testing.T) {
+	// A Tuesday, and every seventh day is always a Tuesday.
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	r, err := ParseRecurrence("FREQ=DAILY;INTERVAL=7;BYDAY=MO", start, time.Hour)
+	log.PanicIf(err)
+
+	if tis := r.Expand(start, start.AddDate(100, 0, 0)); len(tis) != 0 {
+		t.Fatalf("Expected no occurrences: %v", tis)
+	}
+}
+
+func TestRecurrence_Search(t *testing.T) {
+	location := getBerlin()
+	start := time.Date(2024, 3, 5, 2, 0, 0, 0, location)
+
+	r, err := ParseRecurrence("FREQ=WEEKLY;BYDAY=TU", start, time.Hour*2)
+	log.PanicIf(err)
+
+	// Far in the future, so that materializing every occurrence would be
+	// noticeable.
+	matches := r.SearchAndReturn(time.Date(2124, 3, 7, 3, 0, 0, 0, location))
+	if len(matches) != 1 || matches[0].From.Equal(time.Date(2124, 3, 7, 2, 0, 0, 0, location)) == false {
+		t.Fatalf("Matches not correct: %v", matches)
+	}
+
+	// The end time is included.
+	if matches := r.SearchAndReturn(time.Date(2024, 4, 2, 4, 0, 0, 0, location)); len(matches) != 1 {
+		t.Fatalf("Matches not correct: %v", matches)
+	}
+
+	if matches := r.SearchAndReturn(time.Date(2024, 4, 2, 5, 0, 0, 0, location)); len(matches) != 0 {
+		t.Fatalf("Expected no matches: %v", matches)
+	}
+
+	// Before the start.
+	if matches := r.SearchAndReturn(time.Date(2024, 2, 27, 3, 0, 0, 0, location)); len(matches) != 0 {
+		t.Fatalf("Expected no matches: %v", matches)
+	}
+}
+
+func TestRecurrence_Search_MatchesExpand(t *testing.T) {
+	start := time.Date(2024, 1, 1, 22, 30, 0, 0, time.UTC)
+
+	r, err := ParseRecurrence("FREQ=DAILY;INTERVAL=3;BYHOUR=10,22", start, time.Hour*13)
+	log.PanicIf(err)
+
+	from := start.AddDate(0, 0, -2)
+	to := start.AddDate(0, 1, 0)
+
+	tis := r.Expand(from, to)
+
+	for when := from; when.Before(to) == true; when = when.Add(time.Minute * 30) {
+		expected := 0
+		for _, ti := range tis {
+			if when.Before(ti.From) == false && when.After(ti.To) == false {
+				expected++
+			}
+		}
+
+		if matches := r.SearchAndReturn(when); len(matches) != expected {
+			t.Fatalf("Matches for [%s] not correct: (%d) != (%d)", when, len(matches), expected)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package timeindex
+
+import (
+	"sort"
+	"time"
+)
+
+// LastWithClock returns the entries from `d` before the clock's current time
+// up to and including the current time. The result shares memory with `ts`.
+func (ts TimeSlice) LastWithClock(clock Clock, d time.Duration) TimeSlice {
+	now := clock.Now()
+	from := now.Add(-d)
+
+	i := sort.Search(len(ts), func(j int) bool {
+		return ts[j].Time.Before(from) == false
+	})
+
+	j := sort.Search(len(ts), func(j int) bool {
+		return ts[j].Time.After(now)
+	})
+
+	if j < i {
+		j = i
+	}
+
+	return ts[i:j]
+}
+
+// Last returns the entries in the last `d` according to `DefaultClock`.
+func (ts TimeSlice) Last(d time.Duration) TimeSlice {
+	return ts.LastWithClock(DefaultClock, d)
+}
+
+// overlapping returns the intervals that overlap [from, to], both inclusive.
+func (tis TimeIntervalSlice) overlapping(from, to time.Time) TimeIntervalSlice {
+	// Only the intervals that start by `to` can overlap.
+	n := sort.Search(len(tis), func(j int) bool {
+		return tis[j].From.After(to)
+	})
+
+	matches := make(TimeIntervalSlice, 0)
+	for _, ti := range tis[:n] {
+		if ti.To.Before(from) == false {
+			matches = append(matches, ti)
+		}
+	}
+
+	return matches
+}
+
+// ActiveAtWithClock returns the intervals that contain the clock's current
+// time (including at their end times, as with `Search`).
+func (tis TimeIntervalSlice) ActiveAtWithClock(clock Clock) TimeIntervalSlice {
+	now := clock.Now()
+	return tis.overlapping(now, now)
+}
+
+// ActiveNow returns the intervals that contain the current time according to
+// `DefaultClock`.
+func (tis TimeIntervalSlice) ActiveNow() TimeIntervalSlice {
+	return tis.ActiveAtWithClock(DefaultClock)
+}
+
+// LastWithClock returns the intervals that overlap the period from `d` before
+// the clock's current time up to the current time.
+func (tis TimeIntervalSlice) LastWithClock(clock Clock, d time.Duration) TimeIntervalSlice {
+	now := clock.Now()
+	return tis.overlapping(now.Add(-d), now)
+}
+
+// Last returns the intervals that overlap the last `d` according to
+// `DefaultClock`.
+func (tis TimeIntervalSlice) Last(d time.Duration) TimeIntervalSlice {
+	return tis.LastWithClock(DefaultClock, d)
+}
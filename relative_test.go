@@ -0,0 +1,82 @@
+package timeindex
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeSlice_LastWithClock(t *testing.T) {
+	now := getTestEpoch()
+	fc := NewFakeClock(now)
+
+	ts := make(TimeSlice, 0)
+	for minutes := -30; minutes <= 5; minutes += 5 {
+		ts = ts.Add(getTestMinute(minutes), minutes)
+	}
+
+	last := ts.LastWithClock(fc, time.Minute*10)
+	if len(last) != 3 || last[0].Items[0] != -10 || last[2].Items[0] != 0 {
+		t.Fatalf("Entries not correct: %v", last)
+	}
+
+	fc.Advance(time.Hour)
+
+	if last := ts.LastWithClock(fc, time.Minute*10); len(last) != 0 {
+		t.Fatalf("Expected no entries: %v", last)
+	}
+}
+
+func TestTimeSlice_LastWithClock_Future(t *testing.T) {
+	now := getTestEpoch()
+	fc := NewFakeClock(now)
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(now.Add(-time.Hour), "old")
+	ts = ts.Add(now.Add(-time.Minute), "recent")
+	ts = ts.Add(now.Add(time.Minute), "future")
+
+	if last := ts.LastWithClock(fc, time.Minute*10); len(last) != 1 || last[0].Items[0] != "recent" {
+		t.Fatalf("Entries not correct: %v", last)
+	}
+}
+
+func TestTimeIntervalSlice_ActiveAtWithClock(t *testing.T) {
+	now := getTestEpoch()
+	fc := NewFakeClock(now)
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(now.Add(-time.Hour), now.Add(time.Hour), "long")
+	tis = tis.Add(now.Add(-time.Minute*30), now.Add(-time.Minute*20), "ended")
+	tis = tis.Add(now.Add(-time.Minute), now, "ending")
+	tis = tis.Add(now.Add(time.Minute), now.Add(time.Minute*2), "future")
+
+	active := tis.ActiveAtWithClock(fc)
+	if len(active) != 2 || active[0].Items[0] != "long" || active[1].Items[0] != "ending" {
+		t.Fatalf("Active intervals not correct: %v", active)
+	}
+
+	fc.Advance(time.Minute * 90)
+
+	if active := tis.ActiveAtWithClock(fc); len(active) != 0 {
+		t.Fatalf("Expected no active intervals: %v", active)
+	}
+}
+
+func TestTimeIntervalSlice_LastWithClock(t *testing.T) {
+	now := getTestEpoch()
+	fc := NewFakeClock(now)
+
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(now.Add(-time.Hour), now.Add(-time.Minute*50), "old")
+	tis = tis.Add(now.Add(-time.Minute*30), now.Add(-time.Minute*20), "recent")
+	tis = tis.Add(now.Add(-time.Hour*2), now.Add(time.Hour), "long")
+
+	last := tis.LastWithClock(fc, time.Minute*45)
+	if len(last) != 2 || last[0].Items[0] != "long" || last[1].Items[0] != "recent" {
+		t.Fatalf("Intervals not correct: %v", last)
+	}
+
+	if last := tis.LastWithClock(fc, time.Hour); len(last) != 3 {
+		t.Fatalf("Intervals not correct: %v", last)
+	}
+}
@@ -0,0 +1,154 @@
+package timeindex
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Floor returns the index of the last entry at or before `t`.
+func (ts TimeSlice) Floor(t time.Time) (i int, found bool) {
+	i = sort.Search(len(ts), func(j int) bool {
+		return ts[j].Time.After(t)
+	})
+
+	if i == 0 {
+		return -1, false
+	}
+
+	return i - 1, true
+}
+
+// Ceiling returns the index of the first entry at or after `t`.
+func (ts TimeSlice) Ceiling(t time.Time) (i int, found bool) {
+	i = sort.Search(len(ts), func(j int) bool {
+		return ts[j].Time.Before(t) == false
+	})
+
+	if i == len(ts) {
+		return -1, false
+	}
+
+	return i, true
+}
+
+// InterpolationMethod is how `Resample` fills in values between entries.
+type InterpolationMethod int
+
+const (
+	// InterpolatePrevious uses the value of the last entry at or before the
+	// time.
+	InterpolatePrevious InterpolationMethod = iota
+
+	// InterpolateLinear interpolates between the entries on either side.
+	InterpolateLinear
+
+	// InterpolateNearest uses the value of the closest entry (the earlier one
+	// on a tie).
+	InterpolateNearest
+)
+
+// numericSeries returns the entries that have a numeric item, with the value
+// of the first such item as their only item.
+func (ts TimeSlice) numericSeries(extractor NumericExtractor) TimeSlice {
+	series := make(TimeSlice, 0, len(ts))
+	for _, te := range ts {
+		for _, item := range te.Items {
+			if value, ok := extractor(item); ok == true {
+				series = append(series, TimeEntry{Time: te.Time, Items: []interface{}{value}})
+				break
+			}
+		}
+	}
+
+	return series
+}
+
+// Resample returns an entry at every `step` in [from, to) with the value
+// (float64) interpolated from the numeric items of the entries. If `maxGap`
+// is positive, no value is produced where the entries that it would be
+// derived from are farther apart (for linear) or farther away (otherwise)
+// than `maxGap`; such entries have no items. If `extractor` is nil,
+// `NumericItem` is used.
+func (ts TimeSlice) Resample(from, to time.Time, step time.Duration, method InterpolationMethod, extractor NumericExtractor, maxGap time.Duration) (resampled TimeSlice, err error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be positive: [%s]", step)
+	} else if from.Before(to) == false {
+		return nil, fmt.Errorf("range is invalid: [%s] - [%s]", from, to)
+	} else if method != InterpolatePrevious && method != InterpolateLinear && method != InterpolateNearest {
+		return nil, fmt.Errorf("interpolation method (%d) not valid", method)
+	}
+
+	if extractor == nil {
+		extractor = NumericItem
+	}
+
+	series := ts.numericSeries(extractor)
+
+	withinGap := func(d time.Duration) bool {
+		return maxGap <= 0 || d <= maxGap
+	}
+
+	resampled = make(TimeSlice, 0)
+	for t := from; t.Before(to) == true; t = t.Add(step) {
+		te := TimeEntry{
+			Time:  t,
+			Items: []interface{}{},
+		}
+
+		floor, hasFloor := series.Floor(t)
+		ceiling, hasCeiling := series.Ceiling(t)
+
+		var value float64
+		found := false
+
+		if hasFloor == true && series[floor].Time.Equal(t) == true {
+			value = series[floor].Items[0].(float64)
+			found = true
+		} else {
+			switch method {
+			case InterpolatePrevious:
+				if hasFloor == true && withinGap(t.Sub(series[floor].Time)) == true {
+					value = series[floor].Items[0].(float64)
+					found = true
+				}
+			case InterpolateLinear:
+				if hasFloor == true && hasCeiling == true {
+					left, right := series[floor], series[ceiling]
+					span := right.Time.Sub(left.Time)
+
+					if withinGap(span) == true {
+						leftValue := left.Items[0].(float64)
+						rightValue := right.Items[0].(float64)
+						fraction := float64(t.Sub(left.Time)) / float64(span)
+
+						value = leftValue + (rightValue-leftValue)*fraction
+						found = true
+					}
+				}
+			case InterpolateNearest:
+				nearest := -1
+				if hasFloor == true {
+					nearest = floor
+				}
+
+				if hasCeiling == true && (nearest == -1 || series[ceiling].Time.Sub(t) < t.Sub(series[floor].Time)) {
+					nearest = ceiling
+				}
+
+				if nearest != -1 && withinGap(AbsoluteDistance(t, series[nearest].Time)) == true {
+					value = series[nearest].Items[0].(float64)
+					found = true
+				}
+			}
+		}
+
+		if found == true {
+			te.Items = []interface{}{value}
+		}
+
+		resampled = append(resampled, te)
+	}
+
+	return resampled, nil
+}
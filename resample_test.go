@@ -0,0 +1,102 @@
+package timeindex
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getResampleTestSlice() (ts TimeSlice) {
+	ts = make(TimeSlice, 0)
+	ts = ts.Add(getTestSecond(0), 10)
+	ts = ts.Add(getTestSecond(4), 30.0)
+	ts = ts.Add(getTestSecond(5), "not a number")
+	ts = ts.Add(getTestSecond(20), 0)
+
+	return ts
+}
+
+func TestTimeSlice_Floor(t *testing.T) {
+	ts := getResampleTestSlice()
+
+	if i, found := ts.Floor(getTestSecond(-1)); found == true {
+		t.Fatalf("Expected no floor before the first entry: (%d)", i)
+	} else if i, found := ts.Floor(getTestSecond(0)); found == false || i != 0 {
+		t.Fatalf("Floor at an entry not correct: (%d)", i)
+	} else if i, found := ts.Floor(getTestSecond(3)); found == false || i != 0 {
+		t.Fatalf("Floor between entries not correct: (%d)", i)
+	} else if i, found := ts.Floor(getTestSecond(100)); found == false || i != 3 {
+		t.Fatalf("Floor after the last entry not correct: (%d)", i)
+	}
+}
+
+func TestTimeSlice_Ceiling(t *testing.T) {
+	ts := getResampleTestSlice()
+
+	if i, found := ts.Ceiling(getTestSecond(-1)); found == false || i != 0 {
+		t.Fatalf("Ceiling before the first entry not correct: (%d)", i)
+	} else if i, found := ts.Ceiling(getTestSecond(4)); found == false || i != 1 {
+		t.Fatalf("Ceiling at an entry not correct: (%d)", i)
+	} else if i, found := ts.Ceiling(getTestSecond(6)); found == false || i != 3 {
+		t.Fatalf("Ceiling between entries not correct: (%d)", i)
+	} else if i, found := ts.Ceiling(getTestSecond(21)); found == true {
+		t.Fatalf("Expected no ceiling after the last entry: (%d)", i)
+	}
+}
+
+func resampledValues(ts TimeSlice) []interface{} {
+	values := make([]interface{}, len(ts))
+	for i, te := range ts {
+		if len(te.Items) > 0 {
+			values[i] = te.Items[0]
+		}
+	}
+
+	return values
+}
+
+func TestTimeSlice_Resample(t *testing.T) {
+	ts := getResampleTestSlice()
+
+	// The points are at -4, 0, 4, 8, 12, 16, and 20 seconds. The entry at five
+	// seconds has no number and is ignored.
+	cases := []struct {
+		method   InterpolationMethod
+		maxGap   time.Duration
+		expected []interface{}
+	}{
+		{InterpolatePrevious, 0, []interface{}{nil, 10.0, 30.0, 30.0, 30.0, 30.0, 0.0}},
+		{InterpolatePrevious, time.Second * 5, []interface{}{nil, 10.0, 30.0, 30.0, nil, nil, 0.0}},
+		{InterpolateLinear, 0, []interface{}{nil, 10.0, 30.0, 22.5, 15.0, 7.5, 0.0}},
+		{InterpolateLinear, time.Second * 10, []interface{}{nil, 10.0, 30.0, nil, nil, nil, 0.0}},
+		{InterpolateNearest, 0, []interface{}{10.0, 10.0, 30.0, 30.0, 30.0, 0.0, 0.0}},
+		{InterpolateNearest, time.Second * 3, []interface{}{nil, 10.0, 30.0, nil, nil, nil, 0.0}},
+	}
+
+	for i, c := range cases {
+		resampled, err := ts.Resample(getTestSecond(-4), getTestSecond(24), time.Second*4, c.method, nil, c.maxGap)
+		log.PanicIf(err)
+
+		if len(resampled) != 7 || resampled[0].Time.Equal(getTestSecond(-4)) == false || resampled[6].Time.Equal(getTestSecond(20)) == false {
+			t.Fatalf("Case (%d) times not correct: %v", i, resampled)
+		}
+
+		if values := resampledValues(resampled); reflect.DeepEqual(values, c.expected) == false {
+			t.Fatalf("Case (%d) not correct: %v", i, values)
+		}
+	}
+}
+
+func TestTimeSlice_Resample_Invalid(t *testing.T) {
+	ts := getResampleTestSlice()
+
+	if _, err := ts.Resample(getTestSecond(0), getTestSecond(10), 0, InterpolateLinear, nil, 0); err == nil {
+		t.Fatalf("Expected error for zero step.")
+	} else if _, err := ts.Resample(getTestSecond(10), getTestSecond(0), time.Second, InterpolateLinear, nil, 0); err == nil {
+		t.Fatalf("Expected error for inverted range.")
+	} else if _, err := ts.Resample(getTestSecond(0), getTestSecond(10), time.Second, InterpolationMethod(99), nil, 0); err == nil {
+		t.Fatalf("Expected error for unknown method.")
+	}
+}
@@ -0,0 +1,231 @@
+package timeindex
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// TruncateBefore returns a copy of the slice without the entries before `t`.
+// Since it is a copy, the memory of the dropped entries can be released.
+func (ts TimeSlice) TruncateBefore(t time.Time) TimeSlice {
+	i := sort.Search(len(ts), func(j int) bool {
+		return ts[j].Time.Before(t) == false
+	})
+
+	return append(make(TimeSlice, 0, len(ts)-i), ts[i:]...)
+}
+
+// TruncateAfter returns a copy of the slice without the entries after `t`.
+func (ts TimeSlice) TruncateAfter(t time.Time) TimeSlice {
+	i := sort.Search(len(ts), func(j int) bool {
+		return ts[j].Time.After(t)
+	})
+
+	return append(make(TimeSlice, 0, i), ts[:i]...)
+}
+
+// TruncateBefore returns a copy of the slice without the intervals that ended
+// before `t`.
+func (tis TimeIntervalSlice) TruncateBefore(t time.Time) TimeIntervalSlice {
+	// The intervals are sorted by start time, so the ones that ended can be
+	// anywhere.
+	count := 0
+	for _, ti := range tis {
+		if ti.To.Before(t) == false {
+			count++
+		}
+	}
+
+	truncated := make(TimeIntervalSlice, 0, count)
+	for _, ti := range tis {
+		if ti.To.Before(t) == false {
+			truncated = append(truncated, ti)
+		}
+	}
+
+	return truncated
+}
+
+// TruncateAfter returns a copy of the slice without the intervals that start
+// after `t`.
+func (tis TimeIntervalSlice) TruncateAfter(t time.Time) TimeIntervalSlice {
+	i := sort.Search(len(tis), func(j int) bool {
+		return tis[j].From.After(t)
+	})
+
+	return append(make(TimeIntervalSlice, 0, i), tis[:i]...)
+}
+
+// RetentionPolicy limits how much a `ManagedTimeSlice` keeps. Zero values
+// mean no limit.
+type RetentionPolicy struct {
+	// MaxAge drops the entries that are older than this according to the
+	// slice's clock. Entries that expire between writes are no longer
+	// returned and are dropped on the next write.
+	MaxAge time.Duration
+
+	// MaxEntries drops the oldest entries beyond this many.
+	MaxEntries int
+}
+
+// ManagedTimeSlice is a `TimeSlice` that is safe for concurrent use and that
+// applies a retention policy whenever something is added.
+type ManagedTimeSlice struct {
+	lock   sync.RWMutex
+	ts     TimeSlice
+	policy RetentionPolicy
+	clock  Clock
+
+	// dropped is the number of dropped entries that may still be held in
+	// front of `ts` in its underlying array.
+	dropped int
+}
+
+// NewManagedTimeSlice returns a `ManagedTimeSlice` that measures ages with
+// `DefaultClock`.
+func NewManagedTimeSlice(policy RetentionPolicy) *ManagedTimeSlice {
+	return NewManagedTimeSliceWithClock(policy, DefaultClock)
+}
+
+func NewManagedTimeSliceWithClock(policy RetentionPolicy, clock Clock) *ManagedTimeSlice {
+	return &ManagedTimeSlice{
+		ts:     make(TimeSlice, 0),
+		policy: policy,
+		clock:  clock,
+	}
+}
+
+// Add adds the item at the given time and then applies the retention policy
+// (so an item older than the policy allows is dropped immediately).
+func (mts *ManagedTimeSlice) Add(t time.Time, data interface{}) {
+	mts.lock.Lock()
+	defer mts.lock.Unlock()
+
+	mts.ts = mts.ts.Add(t, data)
+	mts.applyPolicy()
+}
+
+// expired returns the number of leading entries that are older than the
+// policy's maximum age.
+func (mts *ManagedTimeSlice) expired() int {
+	if mts.policy.MaxAge <= 0 || len(mts.ts) == 0 {
+		return 0
+	}
+
+	horizon := mts.clock.Now().Add(-mts.policy.MaxAge)
+
+	return sort.Search(len(mts.ts), func(j int) bool {
+		return mts.ts[j].Time.Before(horizon) == false
+	})
+}
+
+// retained returns the entries that haven't expired since the last write.
+func (mts *ManagedTimeSlice) retained() TimeSlice {
+	return mts.ts[mts.expired():]
+}
+
+// applyPolicy drops the entries that the policy doesn't allow. The dropped
+// entries are released once they account for half of the slice.
+func (mts *ManagedTimeSlice) applyPolicy() {
+	i := mts.expired()
+
+	if mts.policy.MaxEntries > 0 && len(mts.ts)-i > mts.policy.MaxEntries {
+		i = len(mts.ts) - mts.policy.MaxEntries
+	}
+
+	if i == 0 {
+		return
+	}
+
+	mts.ts = mts.ts[i:]
+	mts.dropped += i
+
+	if mts.dropped >= len(mts.ts) {
+		mts.ts = append(make(TimeSlice, 0, len(mts.ts)), mts.ts...)
+		mts.dropped = 0
+	}
+}
+
+// SetPolicy changes the retention policy and applies it.
+func (mts *ManagedTimeSlice) SetPolicy(policy RetentionPolicy) {
+	mts.lock.Lock()
+	defer mts.lock.Unlock()
+
+	mts.policy = policy
+	mts.applyPolicy()
+}
+
+// TruncateBefore drops the entries before `t`.
+func (mts *ManagedTimeSlice) TruncateBefore(t time.Time) {
+	mts.lock.Lock()
+	defer mts.lock.Unlock()
+
+	mts.ts = mts.ts.TruncateBefore(t)
+	mts.dropped = 0
+}
+
+// TruncateAfter drops the entries after `t`.
+func (mts *ManagedTimeSlice) TruncateAfter(t time.Time) {
+	mts.lock.Lock()
+	defer mts.lock.Unlock()
+
+	mts.ts = mts.ts.TruncateAfter(t)
+	mts.dropped = 0
+}
+
+// Len returns the number of entries.
+func (mts *ManagedTimeSlice) Len() int {
+	mts.lock.RLock()
+	defer mts.lock.RUnlock()
+
+	return len(mts.retained())
+}
+
+// Search returns a copy of the entry at exactly `t`, if there is one. The
+// items themselves are shared.
+func (mts *ManagedTimeSlice) Search(t time.Time) (te TimeEntry, found bool) {
+	mts.lock.RLock()
+	defer mts.lock.RUnlock()
+
+	ts := mts.retained()
+
+	i := ts.Search(t)
+	if i >= len(ts) || ts[i].Time.Equal(t) == false {
+		return TimeEntry{}, false
+	}
+
+	te = TimeEntry{
+		Time:  ts[i].Time,
+		Items: append([]interface{}{}, ts[i].Items...),
+	}
+
+	return te, true
+}
+
+// SearchNearest is like `TimeSlice.SearchNearest`. The callback is called
+// with the read lock held, so it must not modify the slice.
+func (mts *ManagedTimeSlice) SearchNearest(t time.Time, tolerance time.Duration, cb func(t time.Time) error) (err error) {
+	mts.lock.RLock()
+	defer mts.lock.RUnlock()
+
+	return mts.retained().SearchNearest(t, tolerance, cb)
+}
+
+// Snapshot returns a copy of the entries. The items themselves are shared.
+func (mts *ManagedTimeSlice) Snapshot() TimeSlice {
+	mts.lock.RLock()
+	defer mts.lock.RUnlock()
+
+	ts := mts.retained()
+
+	snapshot := make(TimeSlice, len(ts))
+	for i, te := range ts {
+		snapshot[i] = TimeEntry{
+			Time:  te.Time,
+			Items: append([]interface{}{}, te.Items...),
+		}
+	}
+
+	return snapshot
+}
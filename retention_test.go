@@ -0,0 +1,174 @@
+package timeindex
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getRetentionTestSlice() (ts TimeSlice) {
+	ts = make(TimeSlice, 0)
+	for seconds := 0; seconds < 10; seconds++ {
+		ts = ts.Add(getTestSecond(seconds), seconds)
+	}
+
+	return ts
+}
+
+func TestTimeSlice_TruncateBefore(t *testing.T) {
+	ts := getRetentionTestSlice()
+
+	truncated := ts.TruncateBefore(getTestSecond(7))
+	if len(truncated) != 3 || truncated[0].Time.Equal(getTestSecond(7)) == false {
+		t.Fatalf("Truncated slice not correct: %v", truncated)
+	} else if cap(truncated) != 3 {
+		t.Fatalf("Truncated slice not copied: (%d)", cap(truncated))
+	}
+
+	// The original is untouched.
+	truncated[0].Time = getTestSecond(100)
+	if ts[7].Time.Equal(getTestSecond(7)) == false {
+		t.Fatalf("Original slice modified.")
+	}
+
+	if truncated := ts.TruncateBefore(getTestSecond(100)); len(truncated) != 0 {
+		t.Fatalf("Expected empty slice: %v", truncated)
+	}
+}
+
+func TestTimeSlice_TruncateAfter(t *testing.T) {
+	ts := getRetentionTestSlice()
+
+	truncated := ts.TruncateAfter(getTestSecond(2))
+	if len(truncated) != 3 || truncated[2].Time.Equal(getTestSecond(2)) == false || cap(truncated) != 3 {
+		t.Fatalf("Truncated slice not correct: %v", truncated)
+	} else if truncated := ts.TruncateAfter(getTestSecond(-1)); len(truncated) != 0 {
+		t.Fatalf("Expected empty slice: %v", truncated)
+	}
+}
+
+func TestTimeIntervalSlice_Truncate(t *testing.T) {
+	tis := make(TimeIntervalSlice, 0)
+	tis = tis.Add(getTestSecond(0), getTestSecond(100), "long")
+	tis = tis.Add(getTestSecond(1), getTestSecond(2), "short")
+	tis = tis.Add(getTestSecond(10), getTestSecond(20), "late")
+
+	if truncated := tis.TruncateBefore(getTestSecond(5)); len(truncated) != 2 || truncated[0].Items[0] != "long" || truncated[1].Items[0] != "late" {
+		t.Fatalf("TruncateBefore not correct: %v", truncated)
+	} else if truncated := tis.TruncateAfter(getTestSecond(5)); len(truncated) != 2 || truncated[1].Items[0] != "short" {
+		t.Fatalf("TruncateAfter not correct: %v", truncated)
+	}
+}
+
+func TestManagedTimeSlice_MaxAge(t *testing.T) {
+	clock := NewFakeClock(getTestSecond(99))
+	mts := NewManagedTimeSliceWithClock(RetentionPolicy{MaxAge: time.Second * 5}, clock)
+
+	for seconds := 0; seconds < 100; seconds++ {
+		mts.Add(getTestSecond(seconds), seconds)
+	}
+
+	snapshot := mts.Snapshot()
+	if len(snapshot) != 6 || snapshot[0].Time.Equal(getTestSecond(94)) == false {
+		t.Fatalf("Retained entries not correct: %v", snapshot)
+	}
+
+	// Too old to be kept.
+	mts.Add(getTestSecond(10), 10)
+	if mts.Len() != 6 {
+		t.Fatalf("Old entry not dropped: (%d)", mts.Len())
+	}
+
+	// Dropped entries are released.
+	if cap(mts.ts) > 2*mts.Len()+mts.dropped+16 {
+		t.Fatalf("Dropped entries not released: (%d)", cap(mts.ts))
+	}
+
+	// Entries expire without further writes.
+	clock.Advance(time.Second * 3)
+
+	snapshot = mts.Snapshot()
+	if mts.Len() != 3 || len(snapshot) != 3 || snapshot[0].Time.Equal(getTestSecond(97)) == false {
+		t.Fatalf("Entries not expired: %v", snapshot)
+	} else if _, found := mts.Search(getTestSecond(95)); found == true {
+		t.Fatalf("Expired entry found.")
+	}
+
+	clock.Advance(time.Minute)
+
+	if mts.Len() != 0 {
+		t.Fatalf("Entries not expired: (%d)", mts.Len())
+	}
+}
+
+func TestManagedTimeSlice_Search(t *testing.T) {
+	mts := NewManagedTimeSlice(RetentionPolicy{MaxEntries: 10})
+
+	for seconds := 0; seconds < 20; seconds += 2 {
+		mts.Add(getTestSecond(seconds), seconds)
+	}
+
+	te, found := mts.Search(getTestSecond(6))
+	if found == false || te.Items[0] != 6 {
+		t.Fatalf("Entry not correct: %v", te)
+	} else if _, found := mts.Search(getTestSecond(7)); found == true {
+		t.Fatalf("Expected no entry between times.")
+	}
+
+	times := make([]time.Time, 0)
+	cb := func(t time.Time) error {
+		times = append(times, t)
+		return nil
+	}
+
+	err := mts.SearchNearest(getTestSecond(7), time.Second*2, cb)
+	log.PanicIf(err)
+
+	if len(times) != 2 || times[0].Equal(getTestSecond(6)) == false || times[1].Equal(getTestSecond(8)) == false {
+		t.Fatalf("Nearest times not correct: %v", times)
+	}
+}
+
+func TestManagedTimeSlice_MaxEntries(t *testing.T) {
+	mts := NewManagedTimeSlice(RetentionPolicy{MaxEntries: 3})
+
+	for seconds := 0; seconds < 10; seconds++ {
+		mts.Add(getTestSecond(seconds), seconds)
+	}
+
+	snapshot := mts.Snapshot()
+	if len(snapshot) != 3 || snapshot[0].Items[0] != 7 {
+		t.Fatalf("Retained entries not correct: %v", snapshot)
+	}
+
+	mts.SetPolicy(RetentionPolicy{MaxEntries: 1})
+	if snapshot := mts.Snapshot(); len(snapshot) != 1 || snapshot[0].Items[0] != 9 {
+		t.Fatalf("New policy not applied: %v", snapshot)
+	}
+}
+
+func TestManagedTimeSlice_Concurrent(t *testing.T) {
+	mts := NewManagedTimeSlice(RetentionPolicy{MaxEntries: 50})
+
+	wg := new(sync.WaitGroup)
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+
+		go func(worker int) {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				mts.Add(getTestSecond(worker*100+i), i)
+				mts.Len()
+			}
+		}(worker)
+	}
+
+	wg.Wait()
+
+	if mts.Len() != 50 {
+		t.Fatalf("Entry count not correct: (%d)", mts.Len())
+	}
+}
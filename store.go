@@ -0,0 +1,1173 @@
+package timeindex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// Store is a persistent time index. New entries are recorded in a write-ahead
+// log and buffered in memory. When enough have been buffered they are written
+// to a new immutable segment file and the log is reset. Segments are
+// periodically compacted into one.
+//
+// A segment is only used once its footer has been written. A partial segment
+// (the process died while writing it) is removed when the store is opened and
+// its entries are recovered from the log. The footer records the range of
+// segment IDs that the segment covers (more than one after a compaction) and
+// the last log sequence-number that it includes so that neither leftover
+// segments nor log records are applied twice.
+
+const (
+	storeWalFilename = "wal.log"
+
+	storeFileVersion = 1
+
+	// storeMaxRecordSize is a sanity limit used to detect garbage lengths.
+	storeMaxRecordSize = 1 << 30
+)
+
+var (
+	storeSegmentMagic = []byte{'T', 'I', 'X', 'S'}
+	storeFooterMagic  = []byte{'T', 'I', 'X', 'F'}
+	storeWalMagic     = []byte{'T', 'I', 'X', 'W'}
+)
+
+const (
+	// The file header is the magic bytes followed by the version.
+	storeFileHeaderSize = 5
+
+	// The footer is the magic bytes, four uint64s (first segment ID, last
+	// segment ID, last sequence-number, entry count), and a CRC32.
+	storeFooterSize = 4 + 8*4 + 4
+)
+
+var (
+	ErrStoreClosed = errors.New("store closed")
+
+	errRecordChecksum = errors.New("record checksum mismatch")
+)
+
+// StoreOptions configures a `Store`.
+type StoreOptions struct {
+	// ItemCodec converts items to and from bytes.
+	ItemCodec JsonItemCodec
+
+	// SegmentSize is the number of distinct times that are buffered before
+	// they are written to a new segment.
+	SegmentSize int
+
+	// SparseIndexInterval is the number of segment entries between the
+	// positions that are kept in memory.
+	SparseIndexInterval int
+
+	// CompactionThreshold is the number of segments at which all of them will
+	// be compacted into one. Zero disables automatic compaction.
+	CompactionThreshold int
+
+	// SyncWrites flushes the log to disk on every `Add`.
+	SyncWrites bool
+
+	// wrapWriter, if not nil, wraps the writes to every file. The tests use it
+	// to interrupt writes part of the way through.
+	wrapWriter func(filepath string, w io.Writer) io.Writer
+}
+
+var (
+	DefaultStoreOptions = StoreOptions{
+		ItemCodec:           DefaultJsonItemCodec,
+		SegmentSize:         10000,
+		SparseIndexInterval: 128,
+		CompactionThreshold: 8,
+	}
+)
+
+type storeSparseEntry struct {
+	t      int64
+	offset int64
+}
+
+type storeSegment struct {
+	filepath string
+	f        *os.File
+
+	firstId uint64
+	lastId  uint64
+	lastSeq uint64
+	count   uint64
+
+	// dataEnd is the offset of the footer.
+	dataEnd int64
+
+	sparse []storeSparseEntry
+}
+
+// Store is a persistent, append-only time index.
+type Store struct {
+	dir     string
+	options StoreOptions
+
+	lock sync.Mutex
+
+	wal      *os.File
+	nextSeq  uint64
+	memtable TimeSlice
+
+	segments      []*storeSegment
+	nextSegmentId uint64
+
+	isClosed bool
+}
+
+// Open opens (or creates) a store in the given directory with the default
+// options, recovering any entries that were not written to a segment.
+func Open(dir string) (s *Store, err error) {
+	return OpenWithOptions(dir, DefaultStoreOptions)
+}
+
+// OpenWithOptions opens (or creates) a store in the given directory.
+func OpenWithOptions(dir string, options StoreOptions) (s *Store, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if options.ItemCodec == nil {
+		options.ItemCodec = DefaultStoreOptions.ItemCodec
+	}
+
+	if options.SegmentSize <= 0 {
+		options.SegmentSize = DefaultStoreOptions.SegmentSize
+	}
+
+	if options.SparseIndexInterval <= 0 {
+		options.SparseIndexInterval = DefaultStoreOptions.SparseIndexInterval
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	log.PanicIf(err)
+
+	s = &Store{
+		dir:      dir,
+		options:  options,
+		memtable: make(TimeSlice, 0),
+	}
+
+	err = s.loadSegments()
+	log.PanicIf(err)
+
+	err = s.recoverWal()
+	log.PanicIf(err)
+
+	return s, nil
+}
+
+func storeSegmentFilename(firstId, lastId uint64) string {
+	return fmt.Sprintf("segment-%020d-%020d.seg", firstId, lastId)
+}
+
+func parseStoreSegmentFilename(filename string) (firstId, lastId uint64, ok bool) {
+	if strings.HasPrefix(filename, "segment-") == false || strings.HasSuffix(filename, ".seg") == false {
+		return 0, 0, false
+	}
+
+	parts := strings.Split(filename[len("segment-"):len(filename)-len(".seg")], "-")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	firstId, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	lastId, err = strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return firstId, lastId, true
+}
+
+// loadSegments opens every complete segment, removes partial segments, and
+// removes segments that were already compacted into another.
+func (s *Store) loadSegments() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	files, err := ioutil.ReadDir(s.dir)
+	log.PanicIf(err)
+
+	segments := make([]*storeSegment, 0)
+	for _, fi := range files {
+		firstId, lastId, ok := parseStoreSegmentFilename(fi.Name())
+		if ok == false {
+			continue
+		}
+
+		filepath := path.Join(s.dir, fi.Name())
+
+		ss, err := openStoreSegment(filepath, s.options.SparseIndexInterval)
+		if err == errStoreSegmentIncomplete {
+			err := os.Remove(filepath)
+			log.PanicIf(err)
+
+			continue
+		}
+
+		log.PanicIf(err)
+
+		if ss.firstId != firstId || ss.lastId != lastId {
+			log.Panicf("segment footer does not match its filename: [%s]", filepath)
+		}
+
+		segments = append(segments, ss)
+	}
+
+	// Sort by first ID and then with the widest range first so that segments
+	// that were compacted into another follow it.
+	sort.Slice(segments, func(i, j int) bool {
+		if segments[i].firstId != segments[j].firstId {
+			return segments[i].firstId < segments[j].firstId
+		}
+
+		return segments[i].lastId > segments[j].lastId
+	})
+
+	for _, ss := range segments {
+		last := len(s.segments) - 1
+		if last >= 0 && ss.lastId <= s.segments[last].lastId {
+			// Already covered by a compacted segment.
+
+			ss.f.Close()
+
+			err := os.Remove(ss.filepath)
+			log.PanicIf(err)
+
+			continue
+		} else if last >= 0 && ss.firstId <= s.segments[last].lastId {
+			log.Panicf("segments overlap: [%s] [%s]", s.segments[last].filepath, ss.filepath)
+		}
+
+		s.segments = append(s.segments, ss)
+
+		s.nextSegmentId = ss.lastId + 1
+		if ss.lastSeq >= s.nextSeq {
+			s.nextSeq = ss.lastSeq + 1
+		}
+	}
+
+	err = syncStoreDir(s.dir)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// recoverWal replays the log records that are not yet in a segment and
+// truncates a partially-written trailing record.
+func (s *Store) recoverWal() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	filepath := path.Join(s.dir, storeWalFilename)
+
+	f, err := os.OpenFile(filepath, os.O_RDWR|os.O_CREATE, 0644)
+	log.PanicIf(err)
+
+	s.wal = f
+
+	fi, err := f.Stat()
+	log.PanicIf(err)
+
+	if fi.Size() < storeFileHeaderSize {
+		// New, or we died while creating it.
+
+		err := s.resetWal()
+		log.PanicIf(err)
+
+		err = syncStoreDir(s.dir)
+		log.PanicIf(err)
+
+		return nil
+	}
+
+	header := make([]byte, storeFileHeaderSize)
+
+	_, err = io.ReadFull(f, header)
+	log.PanicIf(err)
+
+	err = checkStoreFileHeader(header, storeWalMagic)
+	log.PanicIf(err)
+
+	br := bufio.NewReader(f)
+	offset := int64(storeFileHeaderSize)
+
+	for {
+		payload, n, err := readStoreRecord(br)
+		if err == io.EOF {
+			break
+		} else if err == io.ErrUnexpectedEOF || (err == errRecordChecksum && n > 0 && offset+n == fi.Size()) {
+			// The process died while the last record was being written.
+			// Discard it.
+
+			err := f.Truncate(offset)
+			log.PanicIf(err)
+
+			break
+		} else if err == errRecordChecksum {
+			// Damage before the end of the log isn't from a crash, and
+			// discarding it would also discard the records after it.
+			log.Panicf("log record at offset (%d) is damaged", offset)
+		}
+
+		log.PanicIf(err)
+
+		offset += n
+
+		seq, t, item, err := s.decodeWalRecord(payload)
+		log.PanicIf(err)
+
+		if seq < s.nextSeq {
+			// Already written to a segment.
+			continue
+		}
+
+		s.memtable = s.memtable.Add(t, item)
+		s.nextSeq = seq + 1
+	}
+
+	_, err = f.Seek(offset, io.SeekStart)
+	log.PanicIf(err)
+
+	return nil
+}
+
+func (s *Store) resetWal() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	err = s.wal.Truncate(0)
+	log.PanicIf(err)
+
+	_, err = s.wal.Seek(0, io.SeekStart)
+	log.PanicIf(err)
+
+	_, err = s.options.writer(s.wal).Write(storeFileHeader(storeWalMagic))
+	log.PanicIf(err)
+
+	err = s.wal.Sync()
+	log.PanicIf(err)
+
+	return nil
+}
+
+// syncStoreDir makes the files that were created or removed in the directory
+// durable. Syncing a file doesn't sync its directory entry.
+func syncStoreDir(dir string) (err error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return f.Sync()
+}
+
+// writer returns the writer for a file.
+func (options StoreOptions) writer(f *os.File) io.Writer {
+	if options.wrapWriter == nil {
+		return f
+	}
+
+	return options.wrapWriter(f.Name(), f)
+}
+
+func storeFileHeader(magic []byte) []byte {
+	return append(append([]byte{}, magic...), storeFileVersion)
+}
+
+func checkStoreFileHeader(header []byte, magic []byte) error {
+	if bytes.Equal(header[:4], magic) == false {
+		return fmt.Errorf("store file not recognized")
+	} else if header[4] != storeFileVersion {
+		return fmt.Errorf("store file version (%d) not supported", header[4])
+	}
+
+	return nil
+}
+
+// writeStoreRecord writes a length-prefixed, checksummed record.
+func writeStoreRecord(w io.Writer, payload []byte) (n int, err error) {
+	record := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(payload)+4)
+	record = record[:binary.PutUvarint(record, uint64(len(payload)))]
+	record = append(record, payload...)
+
+	checksum := make([]byte, 4)
+	binary.BigEndian.PutUint32(checksum, crc32.ChecksumIEEE(payload))
+	record = append(record, checksum...)
+
+	return w.Write(record)
+}
+
+// readStoreRecord reads one record. It returns `io.EOF` if there are no more
+// records and `io.ErrUnexpectedEOF` or `errRecordChecksum` if the record is
+// incomplete or damaged. For a damaged record, `n` is its length (or zero if
+// even that is damaged).
+func readStoreRecord(br *bufio.Reader) (payload []byte, n int64, err error) {
+	size, err := binary.ReadUvarint(br)
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	} else if err != nil {
+		return nil, 0, io.ErrUnexpectedEOF
+	} else if size > storeMaxRecordSize {
+		return nil, 0, errRecordChecksum
+	}
+
+	prefix := make([]byte, binary.MaxVarintLen64)
+	prefixSize := binary.PutUvarint(prefix, size)
+
+	buffer := make([]byte, size+4)
+	if _, err := io.ReadFull(br, buffer); err != nil {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	n = int64(prefixSize) + int64(size) + 4
+
+	payload = buffer[:size]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(buffer[size:]) {
+		return nil, n, errRecordChecksum
+	}
+
+	return payload, n, nil
+}
+
+// encodeStoreItems serializes items as a count followed by length-prefixed
+// encoded items.
+func encodeStoreItems(items []interface{}, codec JsonItemCodec) (encoded []byte, err error) {
+	b := new(bytes.Buffer)
+	buffer := make([]byte, binary.MaxVarintLen64)
+
+	b.Write(buffer[:binary.PutUvarint(buffer, uint64(len(items)))])
+
+	for _, item := range items {
+		raw, err := codec.EncodeItem(item)
+		if err != nil {
+			return nil, err
+		}
+
+		b.Write(buffer[:binary.PutUvarint(buffer, uint64(len(raw)))])
+		b.Write(raw)
+	}
+
+	return b.Bytes(), nil
+}
+
+// splitStoreItems returns the encoded items without decoding them.
+func splitStoreItems(encoded []byte) (raws [][]byte, err error) {
+	count, n := binary.Uvarint(encoded)
+	if n <= 0 || count > uint64(len(encoded)) {
+		return nil, fmt.Errorf("item count not valid")
+	}
+
+	encoded = encoded[n:]
+
+	raws = make([][]byte, count)
+	for i := range raws {
+		size, n := binary.Uvarint(encoded)
+		if n <= 0 || uint64(len(encoded)-n) < size {
+			return nil, fmt.Errorf("item not valid")
+		}
+
+		raws[i] = encoded[n : n+int(size)]
+		encoded = encoded[n+int(size):]
+	}
+
+	return raws, nil
+}
+
+func joinStoreItems(raws [][]byte) []byte {
+	b := new(bytes.Buffer)
+	buffer := make([]byte, binary.MaxVarintLen64)
+
+	b.Write(buffer[:binary.PutUvarint(buffer, uint64(len(raws)))])
+
+	for _, raw := range raws {
+		b.Write(buffer[:binary.PutUvarint(buffer, uint64(len(raw)))])
+		b.Write(raw)
+	}
+
+	return b.Bytes()
+}
+
+func decodeStoreItems(encoded []byte, codec JsonItemCodec) (items []interface{}, err error) {
+	raws, err := splitStoreItems(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	items = make([]interface{}, len(raws))
+	for i, raw := range raws {
+		items[i], err = codec.DecodeItem(raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+func (s *Store) encodeWalRecord(seq uint64, n int64, item interface{}) (payload []byte, err error) {
+	buffer := make([]byte, binary.MaxVarintLen64)
+
+	b := new(bytes.Buffer)
+	b.Write(buffer[:binary.PutUvarint(buffer, seq)])
+
+	binary.BigEndian.PutUint64(buffer, uint64(n))
+	b.Write(buffer[:8])
+
+	if item == nil {
+		b.WriteByte(0)
+	} else {
+		raw, err := s.options.ItemCodec.EncodeItem(item)
+		if err != nil {
+			return nil, err
+		}
+
+		b.WriteByte(1)
+		b.Write(raw)
+	}
+
+	return b.Bytes(), nil
+}
+
+func (s *Store) decodeWalRecord(payload []byte) (seq uint64, t time.Time, item interface{}, err error) {
+	seq, n := binary.Uvarint(payload)
+	if n <= 0 || len(payload) < n+8+1 {
+		return 0, t, nil, fmt.Errorf("log record not valid")
+	}
+
+	payload = payload[n:]
+
+	t = time.Unix(0, int64(binary.BigEndian.Uint64(payload))).UTC()
+
+	if payload[8] == 1 {
+		item, err = s.options.ItemCodec.DecodeItem(payload[9:])
+		if err != nil {
+			return 0, t, nil, err
+		}
+	}
+
+	return seq, t, item, nil
+}
+
+// storeSegmentWriter writes a segment. The segment is not usable until
+// `finish` writes the footer.
+type storeSegmentWriter struct {
+	f     *os.File
+	bw    *bufio.Writer
+	count uint64
+}
+
+func newStoreSegmentWriter(filepath string, options StoreOptions) (ssw *storeSegmentWriter, err error) {
+	f, err := os.OpenFile(filepath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	ssw = &storeSegmentWriter{
+		f:  f,
+		bw: bufio.NewWriter(options.writer(f)),
+	}
+
+	if _, err := ssw.bw.Write(storeFileHeader(storeSegmentMagic)); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return ssw, nil
+}
+
+func (ssw *storeSegmentWriter) write(n int64, encodedItems []byte) (err error) {
+	payload := make([]byte, 8, 8+len(encodedItems))
+	binary.BigEndian.PutUint64(payload, uint64(n))
+	payload = append(payload, encodedItems...)
+
+	if _, err := writeStoreRecord(ssw.bw, payload); err != nil {
+		return err
+	}
+
+	ssw.count++
+
+	return nil
+}
+
+func (ssw *storeSegmentWriter) finish(firstId, lastId, lastSeq uint64) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	defer ssw.f.Close()
+
+	footer := make([]byte, storeFooterSize)
+	copy(footer, storeFooterMagic)
+	binary.BigEndian.PutUint64(footer[4:], firstId)
+	binary.BigEndian.PutUint64(footer[12:], lastId)
+	binary.BigEndian.PutUint64(footer[20:], lastSeq)
+	binary.BigEndian.PutUint64(footer[28:], ssw.count)
+	binary.BigEndian.PutUint32(footer[36:], crc32.ChecksumIEEE(footer[:36]))
+
+	_, err = ssw.bw.Write(footer)
+	log.PanicIf(err)
+
+	err = ssw.bw.Flush()
+	log.PanicIf(err)
+
+	err = ssw.f.Sync()
+	log.PanicIf(err)
+
+	return nil
+}
+
+func (ssw *storeSegmentWriter) abandon() {
+	ssw.f.Close()
+	os.Remove(ssw.f.Name())
+}
+
+var (
+	errStoreSegmentIncomplete = errors.New("segment incomplete")
+)
+
+// openStoreSegment validates a segment and builds its sparse index. It
+// returns `errStoreSegmentIncomplete` if the footer was never written.
+func openStoreSegment(filepath string, sparseInterval int) (ss *storeSegment, err error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			f.Close()
+		}
+	}()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := fi.Size()
+	if size < storeFileHeaderSize+storeFooterSize {
+		return nil, errStoreSegmentIncomplete
+	}
+
+	footer := make([]byte, storeFooterSize)
+	if _, err := f.ReadAt(footer, size-storeFooterSize); err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(footer[:4], storeFooterMagic) == false || crc32.ChecksumIEEE(footer[:36]) != binary.BigEndian.Uint32(footer[36:]) {
+		return nil, errStoreSegmentIncomplete
+	}
+
+	header := make([]byte, storeFileHeaderSize)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+
+	if err := checkStoreFileHeader(header, storeSegmentMagic); err != nil {
+		return nil, err
+	}
+
+	ss = &storeSegment{
+		filepath: filepath,
+		f:        f,
+		firstId:  binary.BigEndian.Uint64(footer[4:]),
+		lastId:   binary.BigEndian.Uint64(footer[12:]),
+		lastSeq:  binary.BigEndian.Uint64(footer[20:]),
+		count:    binary.BigEndian.Uint64(footer[28:]),
+		dataEnd:  size - storeFooterSize,
+		sparse:   make([]storeSparseEntry, 0),
+	}
+
+	// Validate every record and build the sparse index.
+
+	count := uint64(0)
+	cb := func(offset int64, n int64, encodedItems []byte) (bool, error) {
+		if count%uint64(sparseInterval) == 0 {
+			ss.sparse = append(ss.sparse, storeSparseEntry{t: n, offset: offset})
+		}
+
+		count++
+
+		return true, nil
+	}
+
+	if err := ss.scan(storeFileHeaderSize, cb); err != nil {
+		return nil, fmt.Errorf("segment [%s] is damaged: %s", filepath, err)
+	}
+
+	if count != ss.count {
+		return nil, fmt.Errorf("segment [%s] has (%d) records but expected (%d)", filepath, count, ss.count)
+	}
+
+	return ss, nil
+}
+
+// scan reads records starting at the given offset until the callback returns
+// false or the records are exhausted.
+func (ss *storeSegment) scan(offset int64, cb func(offset int64, n int64, encodedItems []byte) (bool, error)) (err error) {
+	sr := io.NewSectionReader(ss.f, offset, ss.dataEnd-offset)
+	br := bufio.NewReader(sr)
+
+	for {
+		payload, size, err := readStoreRecord(br)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		} else if len(payload) < 8 {
+			return fmt.Errorf("segment record not valid")
+		}
+
+		n := int64(binary.BigEndian.Uint64(payload))
+
+		doContinue, err := cb(offset, n, payload[8:])
+		if err != nil {
+			return err
+		} else if doContinue == false {
+			return nil
+		}
+
+		offset += size
+	}
+}
+
+// scanFrom visits the records with times greater-than-or-equal-to `n` using
+// the sparse index to find the starting position.
+func (ss *storeSegment) scanFrom(n int64, cb func(n int64, encodedItems []byte) (bool, error)) (err error) {
+	if len(ss.sparse) == 0 {
+		return nil
+	}
+
+	// Find the last sparse entry that is before the time.
+	i := sort.Search(len(ss.sparse), func(i int) bool {
+		return ss.sparse[i].t >= n
+	})
+
+	if i > 0 {
+		i--
+	}
+
+	wrapper := func(offset int64, recordN int64, encodedItems []byte) (bool, error) {
+		if recordN < n {
+			return true, nil
+		}
+
+		return cb(recordN, encodedItems)
+	}
+
+	return ss.scan(ss.sparse[i].offset, wrapper)
+}
+
+// Add records an item at the given time. As with `TimeSlice.Add`, the item may
+// be nil.
+func (s *Store) Add(t time.Time, data interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isClosed == true {
+		return ErrStoreClosed
+	}
+
+	n, err := toUnixNano(t)
+	log.PanicIf(err)
+
+	payload, err := s.encodeWalRecord(s.nextSeq, n, data)
+	log.PanicIf(err)
+
+	_, err = writeStoreRecord(s.options.writer(s.wal), payload)
+	log.PanicIf(err)
+
+	if s.options.SyncWrites == true {
+		err := s.wal.Sync()
+		log.PanicIf(err)
+	}
+
+	// Keep what was recorded rather than what we were given so that reads
+	// return the same thing before and after the entries are flushed.
+
+	_, recordedTime, recordedItem, err := s.decodeWalRecord(payload)
+	log.PanicIf(err)
+
+	s.nextSeq++
+	s.memtable = s.memtable.Add(recordedTime, recordedItem)
+
+	if len(s.memtable) >= s.options.SegmentSize {
+		err := s.flush()
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// Flush writes the buffered entries to a new segment.
+func (s *Store) Flush() (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isClosed == true {
+		return ErrStoreClosed
+	}
+
+	return s.flush()
+}
+
+func (s *Store) flush() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(s.memtable) == 0 {
+		return nil
+	}
+
+	id := s.nextSegmentId
+	filepath := path.Join(s.dir, storeSegmentFilename(id, id))
+
+	ssw, err := newStoreSegmentWriter(filepath, s.options)
+	log.PanicIf(err)
+
+	for _, te := range s.memtable {
+		encodedItems, err := encodeStoreItems(te.Items, s.options.ItemCodec)
+		if err != nil {
+			ssw.abandon()
+			log.Panic(err)
+		}
+
+		if err := ssw.write(te.Time.UnixNano(), encodedItems); err != nil {
+			ssw.abandon()
+			log.Panic(err)
+		}
+	}
+
+	err = ssw.finish(id, id, s.nextSeq-1)
+	log.PanicIf(err)
+
+	// The segment has to survive a crash before the log can be reset.
+	err = syncStoreDir(s.dir)
+	log.PanicIf(err)
+
+	ss, err := openStoreSegment(filepath, s.options.SparseIndexInterval)
+	log.PanicIf(err)
+
+	s.segments = append(s.segments, ss)
+	s.nextSegmentId++
+
+	// The entries are now in the segment. If we die before the log is reset,
+	// the log records will be skipped by their sequence-numbers.
+
+	err = s.resetWal()
+	log.PanicIf(err)
+
+	s.memtable = make(TimeSlice, 0)
+
+	if s.options.CompactionThreshold > 0 && len(s.segments) >= s.options.CompactionThreshold {
+		err := s.compact()
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// Compact merges all segments into one.
+func (s *Store) Compact() (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isClosed == true {
+		return ErrStoreClosed
+	}
+
+	return s.compact()
+}
+
+// storeSegmentCursor reads the records of a segment one at a time.
+type storeSegmentCursor struct {
+	br *bufio.Reader
+
+	// n and encodedItems are the current record, if `done` is false.
+	n            int64
+	encodedItems []byte
+	done         bool
+}
+
+func newStoreSegmentCursor(ss *storeSegment) (ssc *storeSegmentCursor, err error) {
+	sr := io.NewSectionReader(ss.f, storeFileHeaderSize, ss.dataEnd-storeFileHeaderSize)
+
+	ssc = &storeSegmentCursor{
+		br: bufio.NewReader(sr),
+	}
+
+	if err := ssc.next(); err != nil {
+		return nil, err
+	}
+
+	return ssc, nil
+}
+
+// next moves to the next record.
+func (ssc *storeSegmentCursor) next() (err error) {
+	payload, _, err := readStoreRecord(ssc.br)
+	if err == io.EOF {
+		ssc.done = true
+		ssc.encodedItems = nil
+
+		return nil
+	} else if err != nil {
+		return err
+	} else if len(payload) < 8 {
+		return fmt.Errorf("segment record not valid")
+	}
+
+	ssc.n = int64(binary.BigEndian.Uint64(payload))
+	ssc.encodedItems = payload[8:]
+
+	return nil
+}
+
+func (s *Store) compact() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(s.segments) < 2 {
+		return nil
+	}
+
+	firstId := s.segments[0].firstId
+	lastId := s.segments[len(s.segments)-1].lastId
+
+	lastSeq := uint64(0)
+	for _, ss := range s.segments {
+		if ss.lastSeq > lastSeq {
+			lastSeq = ss.lastSeq
+		}
+	}
+
+	// Read every segment one record at a time so that only the current record
+	// of each is in memory. The items are not decoded.
+
+	cursors := make([]*storeSegmentCursor, len(s.segments))
+	for i, ss := range s.segments {
+		cursors[i], err = newStoreSegmentCursor(ss)
+		log.PanicIf(err)
+	}
+
+	filepath := path.Join(s.dir, storeSegmentFilename(firstId, lastId))
+
+	ssw, err := newStoreSegmentWriter(filepath, s.options)
+	log.PanicIf(err)
+
+	// Merge. When the same time is in more than one segment, the items are
+	// combined in segment order.
+
+	for {
+		least := int64(0)
+		found := false
+		for _, ssc := range cursors {
+			if ssc.done == false && (found == false || ssc.n < least) {
+				least = ssc.n
+				found = true
+			}
+		}
+
+		if found == false {
+			break
+		}
+
+		combined := make([][]byte, 0)
+		for _, ssc := range cursors {
+			if ssc.done == true || ssc.n != least {
+				continue
+			}
+
+			raws, err := splitStoreItems(ssc.encodedItems)
+			if err == nil {
+				combined = append(combined, raws...)
+				err = ssc.next()
+			}
+
+			if err != nil {
+				ssw.abandon()
+				log.Panic(err)
+			}
+		}
+
+		if err := ssw.write(least, joinStoreItems(combined)); err != nil {
+			ssw.abandon()
+			log.Panic(err)
+		}
+	}
+
+	err = ssw.finish(firstId, lastId, lastSeq)
+	log.PanicIf(err)
+
+	err = syncStoreDir(s.dir)
+	log.PanicIf(err)
+
+	ss, err := openStoreSegment(filepath, s.options.SparseIndexInterval)
+	log.PanicIf(err)
+
+	// If we die while removing the old segments, they will be recognized as
+	// covered by the new one and removed when the store is opened.
+
+	for _, old := range s.segments {
+		old.f.Close()
+
+		err := os.Remove(old.filepath)
+		log.PanicIf(err)
+	}
+
+	s.segments = []*storeSegment{ss}
+
+	err = syncStoreDir(s.dir)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// Range returns the entries with times in [from, to).
+func (s *Store) Range(from, to time.Time) (ts TimeSlice, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isClosed == true {
+		return nil, ErrStoreClosed
+	}
+
+	fromN, err := toUnixNano(from)
+	log.PanicIf(err)
+
+	toN, err := toUnixNano(to)
+	log.PanicIf(err)
+
+	ts = make(TimeSlice, 0)
+
+	for _, ss := range s.segments {
+		found := make(TimeSlice, 0)
+
+		cb := func(n int64, encodedItems []byte) (bool, error) {
+			if n >= toN {
+				return false, nil
+			}
+
+			items, err := decodeStoreItems(encodedItems, s.options.ItemCodec)
+			if err != nil {
+				return false, err
+			}
+
+			found = append(found, TimeEntry{Time: time.Unix(0, n).UTC(), Items: items})
+
+			return true, nil
+		}
+
+		err := ss.scanFrom(fromN, cb)
+		log.PanicIf(err)
+
+		ts = MergeTimeSlices(ts, found)
+	}
+
+	i := s.memtable.Search(time.Unix(0, fromN).UTC())
+	j := s.memtable.Search(time.Unix(0, toN).UTC())
+
+	ts = MergeTimeSlices(ts, s.memtable[i:j])
+
+	return ts, nil
+}
+
+// Get returns the items recorded at the given time or `ErrNotFound`.
+func (s *Store) Get(t time.Time) (items []interface{}, err error) {
+	ts, err := s.Range(t, t.Add(time.Nanosecond))
+	if err != nil {
+		return nil, err
+	} else if len(ts) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return ts[0].Items, nil
+}
+
+// Close writes the log to disk and closes the store. Buffered entries are not
+// written to a segment; they will be recovered from the log.
+func (s *Store) Close() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isClosed == true {
+		return nil
+	}
+
+	s.isClosed = true
+
+	for _, ss := range s.segments {
+		ss.f.Close()
+	}
+
+	err = s.wal.Sync()
+	log.PanicIf(err)
+
+	err = s.wal.Close()
+	log.PanicIf(err)
+
+	return nil
+}
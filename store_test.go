@@ -0,0 +1,684 @@
+package timeindex
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getTestStoreSegmentFilenames(dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	log.PanicIf(err)
+
+	filenames := make([]string, 0)
+	for _, fi := range files {
+		if strings.HasSuffix(fi.Name(), ".seg") == true {
+			filenames = append(filenames, fi.Name())
+		}
+	}
+
+	return filenames
+}
+
+func checkTestStoreEntries(t *testing.T, s *Store, count int) {
+	ts, err := s.Range(getTestMinute(0), getTestMinute(count+100))
+	log.PanicIf(err)
+
+	if len(ts) != count {
+		t.Fatalf("Entry count not correct: (%d) != (%d)", len(ts), count)
+	}
+
+	for i, te := range ts {
+		if te.Time.Equal(getTestMinute(i)) == false {
+			t.Fatalf("Entry (%d) has wrong time: [%s]", i, te.Time)
+		} else if reflect.DeepEqual(te.Items, []interface{}{float64(i)}) == false {
+			t.Fatalf("Entry (%d) has wrong items: %v", i, te.Items)
+		}
+	}
+}
+
+func getTestStoreOptions() StoreOptions {
+	options := DefaultStoreOptions
+	options.SegmentSize = 10
+	options.SparseIndexInterval = 3
+	options.CompactionThreshold = 0
+
+	return options
+}
+
+func TestStore_AddAndRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	s, err := OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	// Add out of order.
+	for i := 24; i >= 0; i-- {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	if len(getTestStoreSegmentFilenames(dir)) != 2 {
+		t.Fatalf("Expected two segments: %v", getTestStoreSegmentFilenames(dir))
+	}
+
+	checkTestStoreEntries(t, s, 25)
+
+	ts, err := s.Range(getTestMinute(4), getTestMinute(17))
+	log.PanicIf(err)
+
+	if len(ts) != 13 || ts[0].Time.Equal(getTestMinute(4)) == false || ts[12].Time.Equal(getTestMinute(16)) == false {
+		t.Fatalf("Range not correct: %v", ts)
+	}
+
+	// Add a second item for a time that is already in a segment.
+
+	err = s.Add(getTestMinute(3), "extra")
+	log.PanicIf(err)
+
+	items, err := s.Get(getTestMinute(3))
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(items, []interface{}{float64(3), "extra"}) == false {
+		t.Fatalf("Items not combined: %v", items)
+	}
+
+	_, err = s.Get(getTestMinute(1000))
+	if err != ErrNotFound {
+		t.Fatalf("Expected not-found: %v", err)
+	}
+
+	err = s.Close()
+	log.PanicIf(err)
+
+	err = s.Add(getTestMinute(0), nil)
+	if err != ErrStoreClosed {
+		t.Fatalf("Expected closed error: %v", err)
+	}
+}
+
+func TestStore_Reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	s, err := OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	for i := 0; i < 25; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	err = s.Close()
+	log.PanicIf(err)
+
+	// Five of the entries are only in the log.
+
+	s, err = OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	defer s.Close()
+
+	checkTestStoreEntries(t, s, 25)
+
+	for i := 25; i < 30; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	checkTestStoreEntries(t, s, 30)
+
+	if len(getTestStoreSegmentFilenames(dir)) != 3 {
+		t.Fatalf("Expected three segments: %v", getTestStoreSegmentFilenames(dir))
+	}
+}
+
+func TestStore_Recover_PartialLogRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	s, err := OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	for i := 0; i < 5; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	// Simulate dying in the middle of writing the next record.
+
+	walFilepath := path.Join(dir, storeWalFilename)
+
+	original, err := ioutil.ReadFile(walFilepath)
+	log.PanicIf(err)
+
+	err = s.Add(getTestMinute(5), 5)
+	log.PanicIf(err)
+
+	complete, err := ioutil.ReadFile(walFilepath)
+	log.PanicIf(err)
+
+	// Closing only syncs and releases the files, so they are left as they
+	// would be after a crash.
+	err = s.Close()
+	log.PanicIf(err)
+
+	partial := complete[:len(original)+(len(complete)-len(original))/2]
+
+	err = ioutil.WriteFile(walFilepath, partial, 0644)
+	log.PanicIf(err)
+
+	s, err = OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	checkTestStoreEntries(t, s, 5)
+
+	// The partial record should have been discarded so that new records
+	// aren't lost behind it.
+
+	err = s.Add(getTestMinute(5), 5)
+	log.PanicIf(err)
+
+	err = s.Close()
+	log.PanicIf(err)
+
+	s, err = OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	defer s.Close()
+
+	checkTestStoreEntries(t, s, 6)
+}
+
+func TestStore_Recover_DamagedLogRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	s, err := OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	for i := 0; i < 5; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	err = s.Close()
+	log.PanicIf(err)
+
+	// Damage the first record. The records after it are still intact, so this
+	// isn't a partial write.
+
+	walFilepath := path.Join(dir, storeWalFilename)
+
+	data, err := ioutil.ReadFile(walFilepath)
+	log.PanicIf(err)
+
+	data[storeFileHeaderSize+3] ^= 0xff
+
+	err = ioutil.WriteFile(walFilepath, data, 0644)
+	log.PanicIf(err)
+
+	if _, err := OpenWithOptions(dir, getTestStoreOptions()); err == nil {
+		t.Fatalf("Expected error for a damaged log record.")
+	}
+
+	// Nothing should have been discarded.
+
+	recovered, err := ioutil.ReadFile(walFilepath)
+	log.PanicIf(err)
+
+	if len(recovered) != len(data) {
+		t.Fatalf("Log was truncated: (%d) != (%d)", len(recovered), len(data))
+	}
+}
+
+func TestStore_Recover_PartialSegment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	s, err := OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	for i := 0; i < 15; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	// Simulate dying in the middle of writing the second segment (which would
+	// have contained what's in the log). Closing only syncs and releases the
+	// files, so they are left as they would be after a crash.
+
+	err = s.Close()
+	log.PanicIf(err)
+
+	firstSegmentFilepath := path.Join(dir, storeSegmentFilename(0, 0))
+
+	data, err := ioutil.ReadFile(firstSegmentFilepath)
+	log.PanicIf(err)
+
+	partialSegmentFilepath := path.Join(dir, storeSegmentFilename(1, 1))
+
+	err = ioutil.WriteFile(partialSegmentFilepath, data[:len(data)/2], 0644)
+	log.PanicIf(err)
+
+	s, err = OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	if _, err := os.Stat(partialSegmentFilepath); os.IsNotExist(err) == false {
+		t.Fatalf("Partial segment not removed.")
+	}
+
+	checkTestStoreEntries(t, s, 15)
+
+	for i := 15; i < 20; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	err = s.Close()
+	log.PanicIf(err)
+
+	s, err = OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	defer s.Close()
+
+	checkTestStoreEntries(t, s, 20)
+}
+
+func TestStore_Recover_LogNotReset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	s, err := OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	for i := 0; i < 9; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	// Simulate dying after the segment was written but before the log was
+	// reset.
+
+	walFilepath := path.Join(dir, storeWalFilename)
+
+	err = s.Add(getTestMinute(9), 9)
+	log.PanicIf(err)
+
+	if len(getTestStoreSegmentFilenames(dir)) != 1 {
+		t.Fatalf("Expected a segment.")
+	}
+
+	err = s.Close()
+	log.PanicIf(err)
+
+	// Rebuild the log as it would have been right before the reset.
+
+	s2, err := OpenWithOptions(path.Join(dir, "scratch"), getTestStoreOptions())
+	log.PanicIf(err)
+
+	for i := 0; i < 9; i++ {
+		err := s2.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	err = s2.Close()
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(path.Join(dir, "scratch", storeWalFilename))
+	log.PanicIf(err)
+
+	err = ioutil.WriteFile(walFilepath, data, 0644)
+	log.PanicIf(err)
+
+	s, err = OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	defer s.Close()
+
+	// The entries should not be doubled.
+	checkTestStoreEntries(t, s, 10)
+}
+
+func TestStore_Compact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	options := getTestStoreOptions()
+	options.CompactionThreshold = 3
+
+	s, err := OpenWithOptions(dir, options)
+	log.PanicIf(err)
+
+	for i := 0; i < 20; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	// Keep a copy of the second segment as it exists before compaction.
+
+	secondSegmentFilename := storeSegmentFilename(1, 1)
+
+	secondSegment, err := ioutil.ReadFile(path.Join(dir, secondSegmentFilename))
+	log.PanicIf(err)
+
+	for i := 20; i < 30; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	filenames := getTestStoreSegmentFilenames(dir)
+	if reflect.DeepEqual(filenames, []string{storeSegmentFilename(0, 2)}) == false {
+		t.Fatalf("Segments not compacted: %v", filenames)
+	}
+
+	checkTestStoreEntries(t, s, 30)
+
+	err = s.Close()
+	log.PanicIf(err)
+
+	// Simulate dying before the old segments were removed.
+
+	err = ioutil.WriteFile(path.Join(dir, secondSegmentFilename), secondSegment, 0644)
+	log.PanicIf(err)
+
+	s, err = OpenWithOptions(dir, options)
+	log.PanicIf(err)
+
+	defer s.Close()
+
+	filenames = getTestStoreSegmentFilenames(dir)
+	if reflect.DeepEqual(filenames, []string{storeSegmentFilename(0, 2)}) == false {
+		t.Fatalf("Compacted segment not removed: %v", filenames)
+	}
+
+	checkTestStoreEntries(t, s, 30)
+}
+
+func TestStore_Compact_CombinesItems(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	s, err := OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	defer s.Close()
+
+	err = s.Add(getTestMinute(0), "a")
+	log.PanicIf(err)
+
+	err = s.Flush()
+	log.PanicIf(err)
+
+	err = s.Add(getTestMinute(0), "b")
+	log.PanicIf(err)
+
+	err = s.Flush()
+	log.PanicIf(err)
+
+	err = s.Compact()
+	log.PanicIf(err)
+
+	items, err := s.Get(getTestMinute(0))
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(items, []interface{}{"a", "b"}) == false {
+		t.Fatalf("Items not combined: %v", items)
+	}
+}
+
+func TestStore_Recover_DamagedSegment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	s, err := OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	for i := 0; i < 10; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	err = s.Close()
+	log.PanicIf(err)
+
+	// A complete segment with a damaged record can not be recovered from the
+	// log.
+
+	segmentFilepath := path.Join(dir, storeSegmentFilename(0, 0))
+
+	data, err := ioutil.ReadFile(segmentFilepath)
+	log.PanicIf(err)
+
+	data[storeFileHeaderSize+3] ^= 0xff
+
+	err = ioutil.WriteFile(segmentFilepath, data, 0644)
+	log.PanicIf(err)
+
+	_, err = OpenWithOptions(dir, getTestStoreOptions())
+	if err == nil {
+		t.Fatalf("Expected error for damaged segment.")
+	}
+}
+
+var (
+	errTestWriteInterrupted = errors.New("write interrupted")
+)
+
+// testInterruptedWriter writes until its budget runs out and then fails, as if
+// the process died part of the way through the write. A negative budget is
+// unlimited.
+type testInterruptedWriter struct {
+	w      io.Writer
+	budget *int
+}
+
+func (tiw testInterruptedWriter) Write(p []byte) (n int, err error) {
+	if *tiw.budget < 0 || len(p) <= *tiw.budget {
+		if *tiw.budget >= 0 {
+			*tiw.budget -= len(p)
+		}
+
+		return tiw.w.Write(p)
+	}
+
+	n, err = tiw.w.Write(p[:*tiw.budget])
+	*tiw.budget = 0
+
+	if err != nil {
+		return n, err
+	}
+
+	return n, errTestWriteInterrupted
+}
+
+// getTestInterruptedStoreOptions returns options whose writes to the files
+// with the given suffix are limited by the budget.
+func getTestInterruptedStoreOptions(suffix string, budget *int) StoreOptions {
+	options := getTestStoreOptions()
+
+	options.wrapWriter = func(filepath string, w io.Writer) io.Writer {
+		if strings.HasSuffix(filepath, suffix) == false {
+			return w
+		}
+
+		return testInterruptedWriter{w: w, budget: budget}
+	}
+
+	return options
+}
+
+// abandonTestStore releases the files of a store without closing it, as if
+// the process had died.
+func abandonTestStore(s *Store) {
+	s.wal.Close()
+
+	for _, ss := range s.segments {
+		ss.f.Close()
+	}
+}
+
+func TestStore_Recover_InterruptedAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	budget := -1
+
+	s, err := OpenWithOptions(dir, getTestInterruptedStoreOptions(storeWalFilename, &budget))
+	log.PanicIf(err)
+
+	for i := 0; i < 5; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	// Die part of the way through the next record.
+
+	budget = 7
+
+	if err := s.Add(getTestMinute(5), 5); err == nil {
+		t.Fatalf("Expected the write to be interrupted.")
+	}
+
+	abandonTestStore(s)
+
+	s, err = OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	checkTestStoreEntries(t, s, 5)
+
+	// The partial record should have been discarded so that new records
+	// aren't lost behind it.
+
+	err = s.Add(getTestMinute(5), 5)
+	log.PanicIf(err)
+
+	err = s.Close()
+	log.PanicIf(err)
+
+	s, err = OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	defer s.Close()
+
+	checkTestStoreEntries(t, s, 6)
+}
+
+func TestStore_Recover_InterruptedFlush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	// Die after the header and part of the first record of the segment.
+	budget := 20
+
+	s, err := OpenWithOptions(dir, getTestInterruptedStoreOptions(".seg", &budget))
+	log.PanicIf(err)
+
+	for i := 0; i < 9; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	// The tenth entry fills the segment.
+	if err := s.Add(getTestMinute(9), 9); err == nil {
+		t.Fatalf("Expected the flush to be interrupted.")
+	} else if filenames := getTestStoreSegmentFilenames(dir); len(filenames) != 1 {
+		t.Fatalf("Expected a partial segment: %v", filenames)
+	}
+
+	abandonTestStore(s)
+
+	s, err = OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	if filenames := getTestStoreSegmentFilenames(dir); len(filenames) != 0 {
+		t.Fatalf("Partial segment not removed: %v", filenames)
+	}
+
+	// Every entry is recovered from the log.
+	checkTestStoreEntries(t, s, 10)
+
+	for i := 10; i < 20; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	err = s.Close()
+	log.PanicIf(err)
+
+	s, err = OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	defer s.Close()
+
+	checkTestStoreEntries(t, s, 20)
+}
+
+func TestStore_Recover_InterruptedCompaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeindex")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dir)
+
+	budget := -1
+
+	s, err := OpenWithOptions(dir, getTestInterruptedStoreOptions(".seg", &budget))
+	log.PanicIf(err)
+
+	for i := 0; i < 30; i++ {
+		err := s.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+	}
+
+	// Die part of the way through writing the compacted segment.
+
+	budget = 50
+
+	if err := s.Compact(); err == nil {
+		t.Fatalf("Expected the compaction to be interrupted.")
+	}
+
+	abandonTestStore(s)
+
+	s, err = OpenWithOptions(dir, getTestStoreOptions())
+	log.PanicIf(err)
+
+	defer s.Close()
+
+	if filenames := getTestStoreSegmentFilenames(dir); len(filenames) != 3 {
+		t.Fatalf("Expected the original segments: %v", filenames)
+	}
+
+	checkTestStoreEntries(t, s, 30)
+}
@@ -250,3 +250,19 @@ func search(n int, f func(int) bool) int {
 	// i == j, f(i-1) == false, and f(j) (= f(i)) == true  =>  answer is i.
 	return i
 }
+
+// compareIntervals orders intervals by start-time and then stop-time (the
+// order maintained by `Add`). It returns (-1), (0), or (1).
+func compareIntervals(a, b TimeInterval) int {
+	if a.From.Before(b.From) {
+		return -1
+	} else if a.From.After(b.From) {
+		return 1
+	} else if a.To.Before(b.To) {
+		return -1
+	} else if a.To.After(b.To) {
+		return 1
+	}
+
+	return 0
+}
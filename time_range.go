@@ -0,0 +1,215 @@
+package timeindex
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	rangeDurationPartRe = regexp.MustCompile(`^(\d+(?:\.\d+)?)(ns|us|µs|ms|s|m|h|d|w)`)
+
+	rangeOffsetRe = regexp.MustCompile(`^(now|today)\s*([+-])\s*(.+)$`)
+
+	rangeTimeLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+)
+
+// rangeDuration is a duration whose days (and weeks) are calendar days.
+type rangeDuration struct {
+	days     int
+	duration time.Duration
+}
+
+// parseRangeDuration parses durations like "15m", "1h30m", "7d", or "2w".
+func parseRangeDuration(s string) (rd rangeDuration, err error) {
+	if s == "" {
+		return rd, fmt.Errorf("duration is empty")
+	}
+
+	for rest := s; rest != ""; {
+		match := rangeDurationPartRe.FindStringSubmatch(rest)
+		if match == nil {
+			return rd, fmt.Errorf("duration not valid: [%s]", s)
+		}
+
+		rest = rest[len(match[0]):]
+
+		switch match[2] {
+		case "d", "w":
+			n, err := strconv.Atoi(match[1])
+			if err != nil {
+				return rd, fmt.Errorf("days must be whole: [%s]", s)
+			}
+
+			if match[2] == "w" {
+				n *= 7
+			}
+
+			rd.days += n
+		default:
+			d, err := time.ParseDuration(match[0])
+			if err != nil {
+				return rd, err
+			}
+
+			rd.duration += d
+		}
+	}
+
+	return rd, nil
+}
+
+// subtractFrom returns `t` minus the duration, counting days in the given
+// location.
+func (rd rangeDuration) subtractFrom(t time.Time, location *time.Location) time.Time {
+	return t.In(location).AddDate(0, 0, -rd.days).Add(-rd.duration)
+}
+
+func (rd rangeDuration) addTo(t time.Time, location *time.Location) time.Time {
+	return t.In(location).AddDate(0, 0, rd.days).Add(rd.duration)
+}
+
+// parseRangeTime parses one end of a range: "now", "today", either of those
+// plus or minus a duration, or an absolute time. `isDate` indicates that the
+// expression was a date without a time.
+func parseRangeTime(expr string, now time.Time, location *time.Location) (t time.Time, isDate bool, err error) {
+	day := Calendar{Unit: CalendarDay, Location: location}
+
+	switch expr {
+	case "now":
+		return now, false, nil
+	case "today":
+		return day.Start(now), false, nil
+	}
+
+	if match := rangeOffsetRe.FindStringSubmatch(expr); match != nil {
+		base := now
+		if match[1] == "today" {
+			base = day.Start(now)
+		}
+
+		rd, err := parseRangeDuration(strings.Replace(match[3], " ", "", -1))
+		if err != nil {
+			return t, false, err
+		}
+
+		if match[2] == "-" {
+			return rd.subtractFrom(base, location), false, nil
+		}
+
+		return rd.addTo(base, location), false, nil
+	}
+
+	for _, layout := range rangeTimeLayouts {
+		if t, err := time.ParseInLocation(layout, strings.ToUpper(expr), location); err == nil {
+			return t, layout == "2006-01-02", nil
+		}
+	}
+
+	return t, false, fmt.Errorf("time not valid: [%s]", expr)
+}
+
+// ParseTimeRange parses a time range typed by a user into a half-open
+// interval [From, To). It accepts:
+//
+//   - "last 15m", "last 1h30m", "last 7d", or "last 2w" (up to now)
+//   - "today", "yesterday", or "this week", "this month", "this quarter",
+//     "this year" (weeks are ISO weeks)
+//   - a date, e.g. "2024-01-01" (that whole day)
+//   - "A..B", where each end is "now", "today", either of those plus or
+//     minus a duration (e.g. "now-1h"), or an absolute time (RFC 3339, or
+//     "2006-01-02", "2006-01-02T15:04", etc.)
+//
+// Days are calendar days in `location` (which defaults to UTC). `clock`
+// defaults to `DefaultClock`.
+func ParseTimeRange(expr string, clock Clock, location *time.Location) (ti TimeInterval, err error) {
+	if clock == nil {
+		clock = DefaultClock
+	}
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	now := clock.Now().In(location)
+
+	expr = strings.ToLower(strings.TrimSpace(expr))
+
+	calendarRange := func(unit CalendarUnit, t time.Time) TimeInterval {
+		c := Calendar{Unit: unit, Location: location}
+
+		return TimeInterval{
+			From:  c.Start(t),
+			To:    c.Next(t),
+			Items: []interface{}{},
+		}
+	}
+
+	switch expr {
+	case "today":
+		return calendarRange(CalendarDay, now), nil
+	case "yesterday":
+		return calendarRange(CalendarDay, now.AddDate(0, 0, -1)), nil
+	case "this week":
+		return calendarRange(CalendarIsoWeek, now), nil
+	case "this month":
+		return calendarRange(CalendarMonth, now), nil
+	case "this quarter":
+		return calendarRange(CalendarQuarter, now), nil
+	case "this year":
+		return calendarRange(CalendarYear, now), nil
+	}
+
+	if strings.HasPrefix(expr, "last ") == true {
+		rd, err := parseRangeDuration(strings.Replace(expr[len("last "):], " ", "", -1))
+		if err != nil {
+			return ti, err
+		}
+
+		ti = TimeInterval{
+			From:  rd.subtractFrom(now, location),
+			To:    now,
+			Items: []interface{}{},
+		}
+	} else if parts := strings.Split(expr, ".."); len(parts) == 2 {
+		from, _, err := parseRangeTime(strings.TrimSpace(parts[0]), now, location)
+		if err != nil {
+			return ti, err
+		}
+
+		to, _, err := parseRangeTime(strings.TrimSpace(parts[1]), now, location)
+		if err != nil {
+			return ti, err
+		}
+
+		ti = TimeInterval{
+			From:  from,
+			To:    to,
+			Items: []interface{}{},
+		}
+	} else {
+		t, isDate, err := parseRangeTime(expr, now, location)
+		if err != nil {
+			return ti, err
+		} else if isDate == false {
+			return ti, fmt.Errorf("range not valid: [%s]", expr)
+		}
+
+		return calendarRange(CalendarDay, t), nil
+	}
+
+	if ti.From.Before(ti.To) == false {
+		return ti, fmt.Errorf("range is empty: [%s]", expr)
+	}
+
+	return ti, nil
+}
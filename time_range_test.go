@@ -0,0 +1,94 @@
+package timeindex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestParseTimeRange(t *testing.T) {
+	location := getNewYork()
+
+	// Wednesday, 2016-03-16 10:30 local.
+	now := time.Date(2016, 3, 16, 10, 30, 0, 0, location)
+	fc := NewFakeClock(now.UTC())
+
+	local := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, location)
+	}
+
+	cases := []struct {
+		expr     string
+		from, to time.Time
+	}{
+		{"last 15m", now.Add(-time.Minute * 15), now},
+		{"  LAST 1h 30m ", now.Add(-time.Minute * 90), now},
+		{"last 7d", local(2016, 3, 9, 10, 30), now},
+		{"last 1w", local(2016, 3, 9, 10, 30), now},
+		{"today", local(2016, 3, 16, 0, 0), local(2016, 3, 17, 0, 0)},
+		{"yesterday", local(2016, 3, 15, 0, 0), local(2016, 3, 16, 0, 0)},
+		{"this week", local(2016, 3, 14, 0, 0), local(2016, 3, 21, 0, 0)},
+		{"this month", local(2016, 3, 1, 0, 0), local(2016, 4, 1, 0, 0)},
+		{"this quarter", local(2016, 1, 1, 0, 0), local(2016, 4, 1, 0, 0)},
+		{"this year", local(2016, 1, 1, 0, 0), local(2017, 1, 1, 0, 0)},
+		{"2016-03-13", local(2016, 3, 13, 0, 0), local(2016, 3, 14, 0, 0)},
+		{"2016-01-01..2016-02-01", local(2016, 1, 1, 0, 0), local(2016, 2, 1, 0, 0)},
+		{"now-1h..now", now.Add(-time.Hour), now},
+		{"today-1d .. today+12h", local(2016, 3, 15, 0, 0), local(2016, 3, 16, 12, 0)},
+		{"2016-03-16T08:00..now", local(2016, 3, 16, 8, 0), now},
+		{"2016-03-16T12:00:00Z..2016-03-16T13:00:00Z", time.Date(2016, 3, 16, 12, 0, 0, 0, time.UTC), time.Date(2016, 3, 16, 13, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		ti, err := ParseTimeRange(c.expr, fc, location)
+		log.PanicIf(err)
+
+		if ti.From.Equal(c.from) == false || ti.To.Equal(c.to) == false {
+			t.Fatalf("Range for [%s] not correct: [%s] - [%s]", c.expr, ti.From, ti.To)
+		}
+	}
+}
+
+func TestParseTimeRange_AcrossDst(t *testing.T) {
+	location := getNewYork()
+
+	// Noon on the day of the spring-forward change.
+	now := time.Date(2016, 3, 13, 12, 0, 0, 0, location)
+	fc := NewFakeClock(now)
+
+	ti, err := ParseTimeRange("last 1d", fc, location)
+	log.PanicIf(err)
+
+	if ti.From.Equal(time.Date(2016, 3, 12, 12, 0, 0, 0, location)) == false || ti.To.Sub(ti.From) != time.Hour*23 {
+		t.Fatalf("Range not correct: [%s] - [%s]", ti.From, ti.To)
+	}
+
+	ti, err = ParseTimeRange("last 24h", fc, location)
+	log.PanicIf(err)
+
+	if ti.To.Sub(ti.From) != time.Hour*24 {
+		t.Fatalf("Range not correct: [%s] - [%s]", ti.From, ti.To)
+	}
+}
+
+func TestParseTimeRange_Defaults(t *testing.T) {
+	fc := NewFakeClock(time.Date(2016, 12, 2, 23, 0, 0, 0, getNewYork()))
+
+	ti, err := ParseTimeRange("today", fc, nil)
+	log.PanicIf(err)
+
+	if ti.From.Equal(time.Date(2016, 12, 3, 0, 0, 0, 0, time.UTC)) == false {
+		t.Fatalf("Range not correct: [%s] - [%s]", ti.From, ti.To)
+	}
+}
+
+func TestParseTimeRange_Invalid(t *testing.T) {
+	fc := NewFakeClock(getTestEpoch())
+
+	for _, expr := range []string{"", "last", "last 5x", "last 1.5d", "now", "now-1h", "tomorrowish", "now..now-1h", "2016-01-01..", "a..b..c"} {
+		if _, err := ParseTimeRange(expr, fc, nil); err == nil {
+			t.Fatalf("Expected error for [%s].", expr)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package timeindex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// WindowAggregator is an `Aggregator` that can also remove an item that it
+// was given, so that a sliding window can be updated incrementally. All of
+// the built-in aggregators implement it or provide one for windows.
+type WindowAggregator interface {
+	Aggregator
+
+	// Remove removes the oldest item that hasn't been removed yet (items
+	// leave a window in the order that they were added).
+	Remove(t time.Time, item interface{})
+}
+
+// windowAggregatorProvider is an `Aggregator` that keeps less state than a
+// window needs and provides a `WindowAggregator` to use in its place.
+type windowAggregatorProvider interface {
+	windowAggregator() WindowAggregator
+}
+
+type windowItem struct {
+	t    time.Time
+	item interface{}
+}
+
+// SlidingWindowStream maintains an aggregate over the trailing window
+// (t - width, t] as items are appended in time order.
+type SlidingWindowStream struct {
+	width      time.Duration
+	aggregator Aggregator
+
+	// window holds the items currently in the window starting at `head`.
+	window []windowItem
+	head   int
+}
+
+func NewSlidingWindowStream(width time.Duration, aggregator Aggregator) *SlidingWindowStream {
+	if width <= 0 {
+		log.Panicf("window width must be positive: [%s]", width)
+	}
+
+	if wap, ok := aggregator.(windowAggregatorProvider); ok == true {
+		aggregator = wap.windowAggregator()
+	}
+
+	aggregator.Reset()
+
+	return &SlidingWindowStream{
+		width:      width,
+		aggregator: aggregator,
+		window:     make([]windowItem, 0),
+	}
+}
+
+// Add appends an item (which may be nil) at the given time, drops the items
+// that have left the window, and returns the new aggregate. It returns
+// `ErrUnsorted` if the time is before the last one added.
+func (sws *SlidingWindowStream) Add(t time.Time, item interface{}) (value interface{}, ok bool, err error) {
+	if len(sws.window) > sws.head && t.Before(sws.window[len(sws.window)-1].t) == true {
+		return nil, false, ErrUnsorted
+	}
+
+	sws.window = append(sws.window, windowItem{t: t, item: item})
+	sws.aggregator.Add(t, item)
+
+	sws.expire(t)
+
+	value, ok = sws.aggregator.Result()
+	return value, ok, nil
+}
+
+// expire removes the items at or before `t` minus the width.
+func (sws *SlidingWindowStream) expire(t time.Time) {
+	cutoff := t.Add(-sws.width)
+
+	wa, isWindowAggregator := sws.aggregator.(WindowAggregator)
+
+	removed := false
+	for ; sws.head < len(sws.window) && sws.window[sws.head].t.After(cutoff) == false; sws.head++ {
+		wi := sws.window[sws.head]
+		if isWindowAggregator == true {
+			wa.Remove(wi.t, wi.item)
+		}
+
+		removed = true
+	}
+
+	// Other aggregators are recomputed from the items still in the window.
+	// This costs O(window) for every expiry, so a `SlidingWindow` with such an
+	// aggregator is O(n*w) rather than linear.
+	if removed == true && isWindowAggregator == false {
+		sws.aggregator.Reset()
+		for _, wi := range sws.window[sws.head:] {
+			sws.aggregator.Add(wi.t, wi.item)
+		}
+	}
+
+	// Reclaim the space of the expired items once they are the majority.
+	if sws.head > len(sws.window)/2 {
+		sws.window = append(sws.window[:0], sws.window[sws.head:]...)
+		sws.head = 0
+	}
+}
+
+// Result returns the current aggregate.
+func (sws *SlidingWindowStream) Result() (value interface{}, ok bool) {
+	return sws.aggregator.Result()
+}
+
+// Len returns the number of items in the window.
+func (sws *SlidingWindowStream) Len() int {
+	return len(sws.window) - sws.head
+}
+
+// SlidingWindow returns, for the time of every entry, the aggregate of the
+// items in the trailing window (t - width, t]. Each result entry has the
+// aggregate as its only item, or no items if there was no result. The
+// entries are visited once.
+func (ts TimeSlice) SlidingWindow(width time.Duration, aggregator Aggregator) (results TimeSlice, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	sws := NewSlidingWindowStream(width, aggregator)
+
+	results = make(TimeSlice, len(ts))
+	for i, te := range ts {
+		var value interface{}
+		var ok bool
+
+		if len(te.Items) == 0 {
+			value, ok, err = sws.Add(te.Time, nil)
+			log.PanicIf(err)
+		}
+
+		for _, item := range te.Items {
+			value, ok, err = sws.Add(te.Time, item)
+			log.PanicIf(err)
+		}
+
+		results[i] = TimeEntry{
+			Time:  te.Time,
+			Items: []interface{}{},
+		}
+
+		if ok == true {
+			results[i].Items = []interface{}{value}
+		}
+	}
+
+	return results, nil
+}
+
+// TumblingWindow returns the aggregate of each non-overlapping window of the
+// given width (aligned to `time.Truncate`) that has a result, at the start of
+// the window.
+func (ts TimeSlice) TumblingWindow(width time.Duration, aggregator Aggregator) (results TimeSlice, err error) {
+	if width <= 0 {
+		return nil, fmt.Errorf("window width must be positive: [%s]", width)
+	}
+
+	results = make(TimeSlice, 0)
+	if len(ts) == 0 {
+		return results, nil
+	}
+
+	cb := func(br BucketResult) error {
+		te := TimeEntry{
+			Time:  br.From,
+			Items: []interface{}{br.Value},
+		}
+
+		results = append(results, te)
+
+		return nil
+	}
+
+	from := ts[0].Time.Truncate(width)
+	to := ts[len(ts)-1].Time.Truncate(width).Add(width)
+
+	if err := ts.Bucket(from, to, width, aggregator, cb); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
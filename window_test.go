@@ -0,0 +1,221 @@
+package timeindex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestTimeSlice_SlidingWindow_Count(t *testing.T) {
+	ts := make(TimeSlice, 0)
+	for _, offset := range []int{0, 60, 120, 299, 300, 301, 900} {
+		ts = ts.Add(getTestSecond(offset), offset)
+	}
+
+	// Two items at the same time.
+	ts = ts.Add(getTestSecond(120), -1)
+
+	results, err := ts.SlidingWindow(time.Minute*5, NewCountAggregator())
+	log.PanicIf(err)
+
+	expected := []int{1, 2, 4, 5, 5, 6, 1}
+
+	if len(results) != len(expected) {
+		t.Fatalf("Result count not correct: (%d)", len(results))
+	}
+
+	for i, count := range expected {
+		if results[i].Time.Equal(ts[i].Time) == false || results[i].Items[0] != count {
+			t.Fatalf("Result (%d) not correct: %v", i, results[i])
+		}
+	}
+}
+
+func TestTimeSlice_SlidingWindow_Mean(t *testing.T) {
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(getTestMinute(0), 10)
+	ts = ts.Add(getTestMinute(30), 20)
+	ts = ts.Add(getTestMinute(60), 30)
+	ts = ts.Add(getTestMinute(70), "not a number")
+	ts = ts.Add(getTestMinute(200), "not a number")
+
+	results, err := ts.SlidingWindow(time.Hour, NewMeanAggregator(nil))
+	log.PanicIf(err)
+
+	if results[0].Items[0] != 10.0 || results[1].Items[0] != 15.0 || results[2].Items[0] != 25.0 || results[3].Items[0] != 25.0 {
+		t.Fatalf("Results not correct: %v", results)
+	} else if len(results[4].Items) != 0 {
+		t.Fatalf("Expected no result for a window without numbers: %v", results[4])
+	}
+}
+
+func TestTimeSlice_SlidingWindow_Max(t *testing.T) {
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(getTestMinute(0), 50)
+	ts = ts.Add(getTestMinute(1), 10)
+	ts = ts.Add(getTestMinute(2), 20)
+	ts = ts.Add(getTestMinute(3), 5)
+
+	// The max is kept in a monotonic deque, so 50 expires before 20.
+	results, err := ts.SlidingWindow(time.Minute*2, NewMaxAggregator(nil))
+	log.PanicIf(err)
+
+	expected := []float64{50, 50, 20, 20}
+	for i, value := range expected {
+		if results[i].Items[0] != value {
+			t.Fatalf("Result (%d) not correct: %v", i, results[i])
+		}
+	}
+}
+
+func TestTimeSlice_TumblingWindow(t *testing.T) {
+	start := getTestEpoch()
+
+	ts := make(TimeSlice, 0)
+	ts = ts.Add(start.Add(time.Second*10), 1)
+	ts = ts.Add(start.Add(time.Second*50), 2)
+	ts = ts.Add(start.Add(time.Minute*3+time.Second), 3)
+
+	results, err := ts.TumblingWindow(time.Minute, NewSumAggregator(nil))
+	log.PanicIf(err)
+
+	if len(results) != 2 {
+		t.Fatalf("Result count not correct: %v", results)
+	} else if results[0].Time.Equal(start) == false || results[0].Items[0] != 3.0 {
+		t.Fatalf("First result not correct: %v", results[0])
+	} else if results[1].Time.Equal(start.Add(time.Minute*3)) == false || results[1].Items[0] != 3.0 {
+		t.Fatalf("Second result not correct: %v", results[1])
+	}
+}
+
+func TestSlidingWindowStream(t *testing.T) {
+	sws := NewSlidingWindowStream(time.Minute, NewSumAggregator(nil))
+
+	for i := 0; i < 1000; i++ {
+		value, ok, err := sws.Add(getTestSecond(i), 1)
+		log.PanicIf(err)
+
+		expected := float64(i + 1)
+		if expected > 60 {
+			expected = 60
+		}
+
+		if ok == false || value != expected {
+			t.Fatalf("Value (%d) not correct: %v", i, value)
+		}
+	}
+
+	if sws.Len() != 60 {
+		t.Fatalf("Window length not correct: (%d)", sws.Len())
+	} else if len(sws.window) > 200 {
+		t.Fatalf("Expired items not reclaimed: (%d)", len(sws.window))
+	}
+
+	_, _, err := sws.Add(getTestEpoch(), 1)
+	if err != ErrUnsorted {
+		t.Fatalf("Expected ErrUnsorted: %v", err)
+	}
+}
+
+func TestTimeSlice_SlidingWindow_Incremental(t *testing.T) {
+	values := []float64{5, 3, 8, 8, 1, 9, 2, 2, 7, 4, 6, 0, 3}
+
+	ts := make(TimeSlice, 0)
+	for i, value := range values {
+		ts = ts.Add(getTestMinute(i), value)
+	}
+
+	width := time.Minute * 3
+
+	cases := []struct {
+		name       string
+		aggregator Aggregator
+		expected   func(window []float64) interface{}
+	}{
+		{"min", NewMinAggregator(nil), func(window []float64) interface{} {
+			min := window[0]
+			for _, value := range window {
+				if value < min {
+					min = value
+				}
+			}
+
+			return min
+		}},
+		{"max", NewMaxAggregator(nil), func(window []float64) interface{} {
+			max := window[0]
+			for _, value := range window {
+				if value > max {
+					max = value
+				}
+			}
+
+			return max
+		}},
+		{"first", NewFirstAggregator(), func(window []float64) interface{} {
+			return window[0]
+		}},
+		{"last", NewLastAggregator(), func(window []float64) interface{} {
+			return window[len(window)-1]
+		}},
+	}
+
+	for _, c := range cases {
+		aggregator := c.aggregator
+		if wap, ok := aggregator.(windowAggregatorProvider); ok == true {
+			aggregator = wap.windowAggregator()
+		}
+
+		if _, ok := aggregator.(WindowAggregator); ok == false {
+			t.Fatalf("Aggregator [%s] is not a WindowAggregator.", c.name)
+		}
+
+		results, err := ts.SlidingWindow(width, c.aggregator)
+		log.PanicIf(err)
+
+		for i := range values {
+			// The window is (t - width, t].
+			first := i - int(width/time.Minute) + 1
+			if first < 0 {
+				first = 0
+			}
+
+			expected := c.expected(values[first : i+1])
+			if results[i].Items[0] != expected {
+				t.Fatalf("Result (%d) for [%s] not correct: %v != %v", i, c.name, results[i].Items[0], expected)
+			}
+		}
+	}
+}
+
+// recountAggregator counts its items but can't remove them.
+type recountAggregator struct {
+	count int
+}
+
+func (ra *recountAggregator) Add(t time.Time, item interface{}) {
+	ra.count++
+}
+
+func (ra *recountAggregator) Result() (value interface{}, ok bool) {
+	return ra.count, true
+}
+
+func (ra *recountAggregator) Reset() {
+	ra.count = 0
+}
+
+func TestSlidingWindowStream_Recompute(t *testing.T) {
+	sws := NewSlidingWindowStream(time.Minute*2, &recountAggregator{})
+
+	expected := []int{1, 2, 2, 2}
+	for i, count := range expected {
+		value, ok, err := sws.Add(getTestMinute(i), i)
+		log.PanicIf(err)
+
+		if ok == false || value != count {
+			t.Fatalf("Result (%d) not correct: %v", i, value)
+		}
+	}
+}